@@ -1,94 +1,72 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"strconv"
 	"strings"
-)
 
-type OpCode int
-const (
-	ADDITION = iota + 1
-	MULTIPLY
-	QUIT = 99
+	"github.com/mrbarge/aoc2019/intcode"
 )
 
-func cycle(program []int, pos int) (int, bool) {
-	finished := false
-	switch program[pos] {
-	case ADDITION:
-		pos1 := program[pos+1]
-		pos2 := program[pos+2]
-		posDest := program[pos+3]
-		program[posDest] = program[pos1] + program[pos2]
-		pos = pos + 4
-	case MULTIPLY:
-		pos1 := program[pos+1]
-		pos2 := program[pos+2]
-		posDest := program[pos+3]
-		program[posDest] = program[pos1] * program[pos2]
-		pos = pos + 4
-	case QUIT:
-		finished = true
-	default:
-		fmt.Printf("Unknown op code: %d\n", program[pos])
-	}
+// some noun/verb pairs send the program off into a loop, so give up on any
+// run that takes longer than this
+const maxSteps = 100000
 
-	return pos, finished
-}
-
-func runUntilHalt(program []int) int {
-	done := false
-	pos := 0
-	for !done {
-		pos, done = cycle(program, pos)
+func runUntilHalt(program []int64) (int64, error) {
+	c := intcode.NewComputer(program)
+	if err := c.RunContext(context.Background(), maxSteps); err != nil {
+		return 0, err
 	}
-	return program[0]
+	result, _ := c.Read(0)
+	return result, nil
 }
 
 func main() {
 
 	partOne := false
+	target := flag.Int64("target", 19690720, "value to find inputs for")
+	addrs := flag.String("addrs", "1,2", "comma separated addresses to solve for")
+	brute := flag.Bool("brute", false, "search every input rather than solving")
 
-	bd, err := ioutil.ReadFile("input.txt")
-	if err != nil {
-		os.Exit(1)
-	}
-	sProg := strings.Split(string(bd), ",")
-	originalProg := make([]int, len(sProg))
-	for i, v := range sProg {
-		originalProg[i], _ = strconv.Atoi(v)
-	}
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	originalProg := intcode.LoadProgram(*programFile)
 
 	if partOne {
 		originalProg[1] = 12
 		originalProg[2] = 2
-		output := runUntilHalt(originalProg)
+		output, err := runUntilHalt(originalProg)
+		if err != nil {
+			panic(err)
+		}
 		fmt.Println(output)
 	} else {
-		done := false
-		noun, verb := 0, 0
-		for noun <= 99 && !done {
-			for verb <= 99 && !done {
-				candidateProg := make([]int, len(originalProg))
-				copy(candidateProg, originalProg)
-				candidateProg[1] = noun
-				candidateProg[2] = verb
-				result := runUntilHalt(candidateProg)
-				if result == 19690720 {
-					done = true
-				} else {
-					verb += 1
-				}
+		search := intcode.InputSearch{Program: originalProg, Lo: 0, Hi: 99, Result: 0, MaxSteps: maxSteps}
+		for _, field := range strings.Split(*addrs, ",") {
+			addr, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				panic(err)
 			}
-			if !done {
-				noun += 1
-				verb = 0
+			search.Unknowns = append(search.Unknowns, addr)
+		}
+		find := search.Find
+		if *brute {
+			// a pair that crashes or never halts just isn't the answer
+			find = search.BruteForce
+		}
+		values, err := find(*target)
+		if err != nil {
+			panic(err)
+		}
+		if u := search.Unknowns; len(u) == 2 && u[0] == 1 && u[1] == 2 {
+			fmt.Printf("Noun: %d, Verb: %d\n", values[0], values[1])
+		} else {
+			for i, addr := range search.Unknowns {
+				fmt.Printf("[%d] = %d\n", addr, values[i])
 			}
 		}
-		fmt.Printf("Noun: %d, Verb: %d\n", noun, verb)
 	}
 
 }
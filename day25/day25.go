@@ -2,276 +2,115 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"strconv"
 	"strings"
-)
 
-type ParameterMode int
-const (
-	POSITION = iota
-	IMMEDIATE
-	RELATIVE
+	"github.com/mrbarge/aoc2019/intcode"
 )
 
-type Packet struct {
-	x int64
-	y int64
-}
-
-type Computer struct {
-	program []int64
-	inputs []int64
-	relativeBase int64
-	pos int64
-	finished bool
-	outputs []int64
-	inputBlocked bool
-}
-
-type OpCode int
-const (
-	ADDITION = iota + 1
-	MULTIPLY		// 2
-	STORE			// 3
-	OUTPUT			// 4
-	JIT				// 5
-	JIF				// 6
-	LT				// 7
-	EQ				// 8
-	RBO				// 9
-	QUIT = 99
-)
-
-func parseOpCode(i int64) OpCode {
-	s := strconv.Itoa(int(i))
-	if len(s) > 1 {
-		r, _ := strconv.Atoi(s[len(s)-2:])
-		return OpCode(r)
-	} else {
-		r, _ := strconv.Atoi(s)
-		return OpCode(r)
-	}
-}
-
-func parseModes(i int64) []ParameterMode {
-	// fill modes with default position mode - generic to cater for numbers > 4 digits
-	s := strconv.Itoa(int(i))
-	rl := 3
-	if len(s) - 3 > 3 {
-		rl = len(s) - 3
-	}
-	r := make([]ParameterMode, rl)
-	for i, _ := range r {
-		r[i] = POSITION
-	}
-
-	ptr := 0
-	for i := len(s)-3; i >= 0; i-- {
-		if s[i] == '0' {
-			r[ptr] = POSITION
-			ptr += 1
-		} else if s[i] == '1' {
-			r[ptr] = IMMEDIATE
-			ptr += 1
-		} else if s[i] == '2' {
-			r[ptr] = RELATIVE
-			ptr += 1
-		} else {
-			fmt.Printf("Error parsing mode: %d\n", i)
-		}
-	}
-	return r
-}
-
-func getPositionOrImmediate(c *Computer, mode ParameterMode, value int64, read bool) int64 {
-	if mode == POSITION {
-		if read {
-			return c.program[value]
-		} else {
-			return value
-		}
-	} else if mode == IMMEDIATE {
-		return value
-	} else if mode == RELATIVE {
-		if read {
-			return c.program[c.relativeBase+value]
-		} else {
-			return c.relativeBase+value
-		}
-	} else {
-		fmt.Printf("Error with mode argument: %d\n", mode)
-		return -1
+func partOne(program []int64, record string)  {
+
+	c := intcode.NewComputer(program)
+	a := intcode.NewASCII(c)
+	var recorder *intcode.Recorder
+	if record != "" {
+		recorder = intcode.NewRecorder()
+		c.SetRecorder(recorder)
+		defer func() {
+			if err := recorder.Save(record); err != nil {
+				fmt.Println(err)
+			}
+		}()
 	}
-}
-
-func cycle(c *Computer) {
-
-	op := parseOpCode(c.program[c.pos])
-	modes := parseModes(c.program[c.pos])
-
-	switch op {
 
-	case ADDITION:
-		value1 := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		value2 := getPositionOrImmediate(c, modes[1], c.program[c.pos+2], true)
-		posDest := getPositionOrImmediate(c, modes[2], c.program[c.pos+3], false)
-		c.program[posDest] = value1 + value2
-		c.pos = c.pos + 4
-
-	case MULTIPLY:
-		value1 := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		value2 := getPositionOrImmediate(c, modes[1], c.program[c.pos+2], true)
-		posDest := getPositionOrImmediate(c, modes[2], c.program[c.pos+3], false)
-		c.program[posDest] = value1 * value2
-		c.pos = c.pos + 4
-
-	case STORE:
-		if len(c.inputs) == 0 {
-			c.inputBlocked = true
-		} else {
-			c.inputBlocked = false
-			posDest := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], false)
-			c.program[posDest] = c.inputs[0]
-			c.inputs = c.inputs[1:]
-			c.pos = c.pos + 2
-		}
-
-	case OUTPUT:
-		posDest := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		c.outputs = append(c.outputs, posDest)
-		c.pos = c.pos + 2
-
-	case JIT:
-		value1 := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		value2 := getPositionOrImmediate(c, modes[1], c.program[c.pos+2], true)
-		if value1 != 0 {
-			c.pos = int64(value2)
-		} else {
-			c.pos += 3
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		text, err := a.ReadUntilPrompt("Command?\n")
+		fmt.Print(text)
+		if err == io.EOF {
+			return
+		} else if err != nil && err != intcode.ErrAwaitingInput {
+			panic(err)
 		}
 
-	case JIF:
-		value1 := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		value2 := getPositionOrImmediate(c, modes[1], c.program[c.pos+2], true)
-		if value1 == 0 {
-			c.pos = int64(value2)
-		} else {
-			c.pos += 3
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			return
 		}
-
-	case LT:
-		value1 := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		value2 := getPositionOrImmediate(c, modes[1], c.program[c.pos+2], true)
-		posDest := getPositionOrImmediate(c, modes[2], c.program[c.pos+3], false)
-		if value1 < value2 {
-			c.program[posDest] = 1
-		} else {
-			c.program[posDest] = 0
+		// "save <file>" and "load <file>" checkpoint the adventure
+		// instead of being sent to the droid
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "save" {
+			if err := c.Snapshot().Save(fields[1]); err != nil {
+				fmt.Println(err)
+			}
+			continue
 		}
-		c.pos += 4
-
-	case EQ:
-		value1 := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		value2 := getPositionOrImmediate(c, modes[1], c.program[c.pos+2], true)
-		posDest := getPositionOrImmediate(c, modes[2], c.program[c.pos+3], false)
-		if value1 == value2 {
-			c.program[posDest] = 1
-		} else {
-			c.program[posDest] = 0
+		if len(fields) == 2 && fields[0] == "load" {
+			if recorder != nil {
+				// a replay can't jump between states
+				fmt.Println("load isn't available while recording")
+				continue
+			}
+			s, err := intcode.LoadSnapshot(fields[1])
+			if err != nil {
+				fmt.Println(err)
+			} else {
+				c.Restore(s)
+			}
+			continue
 		}
-		c.pos += 4
-
-	case RBO:
-		adjustment := getPositionOrImmediate(c, modes[0], c.program[c.pos+1], true)
-		c.relativeBase += adjustment
-		c.pos += 2
-
-	case QUIT:
-		c.finished = true
-
-	default:
-		fmt.Printf("Unknown op code: %d\n", c.program[c.pos])
+		a.WriteLine(strings.TrimSpace(line))
 	}
-}
 
-func addInput(c *Computer, input int64) {
-	c.inputs = append(c.inputs, input)
 }
 
-func popOutput(c *Computer) int64 {
-	if len(c.outputs) > 0 {
-		r := c.outputs[0]
-		c.outputs = c.outputs[1:]
-		return r
-	} else {
-		panic("Computer has no outputs to provide")
-	}
-}
-
-func toInputArr(r []rune) []int64 {
-	m := make([]int64,len(r))
-	for i := range r {
-		m[i] = int64(r[i])
+// replay runs a session recorded with -record, printing what the droid
+// said and whether it still said it at the same points.
+func replay(program []int64, path string) {
+	rp, err := intcode.LoadReplay(path)
+	if err != nil {
+		panic(err)
 	}
-	return m
-}
-
-func partOne(program []int64)  {
-
-	c := Computer{program, []int64{},0, 0, false, []int64{}, false}
-
-	reader := bufio.NewReader(os.Stdin)
-	done := false
-	for !done {
-		// run program, get colour and direction
-		for !c.finished && !c.inputBlocked && len(c.outputs) == 0 {
-			cycle(&c)
-		}
-
-		if len(c.outputs) >= 1 {
-			val := rune(popOutput(&c))
-			fmt.Print(string(val))
-		}
-
-		if c.inputBlocked {
-			text, _ := reader.ReadString('\n')
-			rtext := []rune(text)
-			rtext = append(rtext, '\n')
-			inputdata := toInputArr(rtext)
-			c.inputs = inputdata
-			c.inputBlocked = false
+	c := intcode.NewComputer(program)
+	err = rp.Drive(c)
+	for c.PendingOutputs() > 0 {
+		if v := c.PopOutput(); v < 128 {
+			fmt.Print(string(rune(v)))
+		} else {
+			fmt.Println(v)
 		}
 	}
-
-
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println("replay matched")
 }
 
 func main() {
 
-	bd, err := ioutil.ReadFile("input.txt")
-	if err != nil {
-		os.Exit(1)
-	}
-	sProg := strings.Split(string(bd), ",")
-	originalProg := make([]int64, len(sProg))
-	for i, v := range sProg {
-		originalProg[i], _ = strconv.ParseInt(v, 10, 64)
-	}
+	record := flag.String("record", "", "record the session to this replay file")
+	replayFile := flag.String("replay", "", "replay a recorded session instead of reading stdin")
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	originalProg := intcode.LoadProgram(*programFile)
 
 	candidateProg := make([]int64, len(originalProg))
 	copy(candidateProg, originalProg)
 
-	bufferSpace := make([]int64, 100000)
-	candidateProg = append(candidateProg, bufferSpace...)
-
 	// solution was
 	// - prime number
 	// - asterisk
 	// - sand
 	// - tambourine
-	partOne(candidateProg)
+	if *replayFile != "" {
+		replay(candidateProg, *replayFile)
+		return
+	}
+	partOne(candidateProg, *record)
 }
 
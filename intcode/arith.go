@@ -0,0 +1,195 @@
+package intcode
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Arithmetic selects how ADDITION and MULTIPLY deal with results that don't
+// fit in an int64.
+type Arithmetic int
+
+const (
+	// WRAPPING silently wraps around, like plain Go arithmetic.
+	WRAPPING Arithmetic = iota
+	// CHECKED stops the computer with ErrOverflow.
+	CHECKED
+	// BIG keeps such results exactly. Cells holding them can be added,
+	// multiplied, compared, tested by jumps and output, but using one as an
+	// address, jump target or relative base offset fails with ErrOverflow.
+	BIG
+)
+
+// SetArithmetic switches c to mode a. Values already stored beyond int64
+// stay readable through ReadBig after leaving BIG.
+func (c *Computer) SetArithmetic(a Arithmetic) {
+	c.arith = a
+	if a == BIG && c.bigs == nil {
+		c.bigs = make(map[int64]*big.Int)
+	}
+}
+
+// Arithmetic returns the mode set with SetArithmetic.
+func (c *Computer) Arithmetic() Arithmetic {
+	return c.arith
+}
+
+// ReadBig returns the value held at addr, including values beyond int64.
+func (c *Computer) ReadBig(addr int64) (*big.Int, error) {
+	if v, ok := c.bigs[addr]; ok {
+		return new(big.Int).Set(v), nil
+	}
+	v, err := c.memory.Read(addr)
+	return big.NewInt(v), err
+}
+
+// PopBigOutput removes and returns the oldest pending output, which unlike
+// PopOutput may be beyond int64.
+func (c *Computer) PopBigOutput() *big.Int {
+	if v, ok := c.bigOutputs[0]; ok {
+		c.outputs = c.outputs[1:]
+		c.shiftBigOutputs()
+		return v
+	}
+	return big.NewInt(c.PopOutput())
+}
+
+// bigValue reports why a value beyond int64 can't be used where an int64 is
+// needed.
+func bigValue(addr int64) error {
+	return fmt.Errorf("%w: value at %d needs more than 64 bits", ErrOverflow, addr)
+}
+
+// shiftBigOutputs renumbers the big outputs after the first output has been
+// popped.
+func (c *Computer) shiftBigOutputs() {
+	if len(c.bigOutputs) == 0 {
+		return
+	}
+	shifted := make(map[int]*big.Int, len(c.bigOutputs))
+	for i, v := range c.bigOutputs {
+		if i > 0 {
+			shifted[i-1] = v
+		}
+	}
+	c.bigOutputs = shifted
+}
+
+func addOverflows(a int64, b int64) bool {
+	sum := a + b
+	return (a > 0 && b > 0 && sum < 0) || (a < 0 && b < 0 && sum >= 0)
+}
+
+func mulOverflows(a int64, b int64) bool {
+	if a == 0 || b == 0 {
+		return false
+	}
+	product := a * b
+	return product/b != a || (a == -1 && b == -1<<63) || (b == -1 && a == -1<<63)
+}
+
+// arith runs ADDITION, MULTIPLY, LT or EQ on int64s, honouring CHECKED.
+func (o *operands) arith(op OpCode) {
+	value1 := o.read(1)
+	value2 := o.read(2)
+	var result int64
+	switch op {
+	case ADDITION:
+		if o.c.arith == CHECKED && addOverflows(value1, value2) {
+			o.fail(ErrOverflow, 0)
+		}
+		result = value1 + value2
+	case MULTIPLY:
+		if o.c.arith == CHECKED && mulOverflows(value1, value2) {
+			o.fail(ErrOverflow, 0)
+		}
+		result = value1 * value2
+	case LT:
+		if value1 < value2 {
+			result = 1
+		}
+	case EQ:
+		if value1 == value2 {
+			result = 1
+		}
+	}
+	o.write(o.addr(3), result)
+}
+
+// bigArith is arith for BIG mode.
+func (o *operands) bigArith(op OpCode) {
+	value1 := o.readBig(1)
+	value2 := o.readBig(2)
+	if o.err != nil {
+		return
+	}
+	result := new(big.Int)
+	switch op {
+	case ADDITION:
+		result.Add(value1, value2)
+	case MULTIPLY:
+		result.Mul(value1, value2)
+	case LT:
+		if value1.Cmp(value2) < 0 {
+			result.SetInt64(1)
+		}
+	case EQ:
+		if value1.Cmp(value2) == 0 {
+			result.SetInt64(1)
+		}
+	}
+	o.writeBig(o.addr(3), result)
+}
+
+// readBig is read for parameters that may hold values beyond int64.
+func (o *operands) readBig(n int) *big.Int {
+	value := o.load(o.c.pos + int64(n))
+	addr := value
+	switch o.in.modes[n-1] {
+	case IMMEDIATE:
+		return big.NewInt(value)
+	case RELATIVE:
+		addr = o.c.relativeBase + value
+	}
+	if v, ok := o.c.bigs[addr]; ok {
+		return new(big.Int).Set(v)
+	}
+	return big.NewInt(o.load(addr))
+}
+
+func (o *operands) writeBig(addr int64, value *big.Int) {
+	if value.IsInt64() {
+		o.write(addr, value.Int64())
+		return
+	}
+	// memory holds a placeholder, the real value lives in bigs
+	o.write(addr, 0)
+	if o.err == nil {
+		o.c.bigs[addr] = value
+	}
+}
+
+// nonZero reports whether parameter n is non-zero, for the jumps.
+func (o *operands) nonZero(n int) bool {
+	if o.c.arith == BIG {
+		return o.readBig(n).Sign() != 0
+	}
+	return o.read(n) != 0
+}
+
+func (o *operands) outputBig() {
+	value := o.readBig(1)
+	if o.err != nil {
+		return
+	}
+	c := o.c
+	if value.IsInt64() {
+		c.outputs = append(c.outputs, value.Int64())
+		return
+	}
+	if c.bigOutputs == nil {
+		c.bigOutputs = make(map[int]*big.Int)
+	}
+	c.outputs = append(c.outputs, 0)
+	c.bigOutputs[len(c.outputs)-1] = value
+}
@@ -0,0 +1,113 @@
+package intcode
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestCheckedOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		op       int64
+		a, b     int64
+		overflow bool
+	}{
+		{"add max", 1101, math.MaxInt64, 1, true},
+		{"add min", 1101, math.MinInt64, -1, true},
+		{"add fits", 1101, math.MaxInt64, -1, false},
+		{"multiply", 1102, 1 << 32, 1 << 31, true},
+		{"multiply fits", 1102, 1 << 31, 1 << 31, false},
+		{"negate min", 1102, -1, math.MinInt64, true},
+		{"negative fits", 1102, -1 << 31, 1 << 32, false},
+	} {
+		// [5] = a op b
+		program := []int64{tc.op, tc.a, tc.b, 5, 99, 0}
+		c := NewComputer(program)
+		c.SetArithmetic(CHECKED)
+		err := c.Run()
+		if tc.overflow {
+			var e *Error
+			if !errors.As(err, &e) || !errors.Is(err, ErrOverflow) {
+				t.Errorf("%s: got %v, want ErrOverflow", tc.name, err)
+			} else if e.Pos != 0 || e.Instruction != tc.op {
+				t.Errorf("%s: blamed pos %d instruction %d", tc.name, e.Pos, e.Instruction)
+			}
+			if v, _ := c.Read(5); v != 0 {
+				t.Errorf("%s: [5] = %d after overflowing", tc.name, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+
+		// WRAPPING gives the same answer when it fits
+		w := NewComputer(program)
+		if err := w.Run(); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := c.Read(5)
+		if want, _ := w.Read(5); got != want {
+			t.Errorf("%s: CHECKED got %d, WRAPPING %d", tc.name, got, want)
+		}
+	}
+}
+
+func TestBigArithmetic(t *testing.T) {
+	program := []int64{
+		1102, math.MaxInt64, math.MaxInt64, 30, // [30] = max^2
+		1001, 30, 1, 31, // [31] = [30] + 1
+		7, 30, 31, 32, // [32] = [30] < [31]
+		1002, 31, 0, 33, // [33] = [31] * 0, back in range
+		1007, 30, -1, 34, // [34] = [30] < -1
+		4, 31, // output [31]
+		104, 7, // output 7
+		99,
+	}
+	largest := big.NewInt(math.MaxInt64)
+	square := new(big.Int).Mul(largest, largest)
+	plusOne := new(big.Int).Add(square, big.NewInt(1))
+
+	c := NewComputer(program)
+	c.SetArithmetic(BIG)
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[int64]*big.Int{30: square, 31: plusOne, 32: big.NewInt(1), 33: big.NewInt(0), 34: big.NewInt(0)} {
+		if got, err := c.ReadBig(addr); err != nil || got.Cmp(want) != 0 {
+			t.Errorf("[%d] = %v, %v, want %v", addr, got, err, want)
+		}
+	}
+	if got := c.PopBigOutput(); got.Cmp(plusOne) != 0 {
+		t.Errorf("output %v, want %v", got, plusOne)
+	}
+	if got := c.PopOutput(); got != 7 {
+		t.Errorf("output %d, want 7", got)
+	}
+
+	// the values stay readable after leaving BIG
+	c.SetArithmetic(WRAPPING)
+	if got, _ := c.ReadBig(30); got.Cmp(square) != 0 {
+		t.Errorf("[30] = %v after leaving BIG, want %v", got, square)
+	}
+}
+
+func TestBigAsAddress(t *testing.T) {
+	// each computes 2^64 and then needs it as an int64
+	for _, tc := range []struct {
+		name    string
+		program []int64
+	}{
+		{"relative base offset", []int64{1102, 1 << 32, 1 << 32, 7, 9, 7, 99, 0}},
+		{"jump target", []int64{1102, 1 << 32, 1 << 32, 8, 105, 1, 8, 99, 0}},
+		{"address", []int64{1102, 1 << 32, 1 << 32, 5, 4, 0, 99}},
+	} {
+		c := NewComputer(tc.program)
+		c.SetArithmetic(BIG)
+		if err := c.Run(); !errors.Is(err, ErrOverflow) {
+			t.Errorf("%s: got %v, want ErrOverflow", tc.name, err)
+		}
+	}
+}
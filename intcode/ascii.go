@@ -0,0 +1,194 @@
+package intcode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrAwaitingInput is returned by ASCII reads when the program has stopped
+// for input and has nothing more to say until it gets some.
+var ErrAwaitingInput = errors.New("waiting for input")
+
+// ASCII drives a Computer that talks in lines of text. Outputs are turned
+// into a stream of characters; a value outside the ASCII range, like the
+// final answers days 17 and 21 print, is passed through as its decimal
+// digits on a line of its own and also kept for Values.
+//
+// ASCII is an io.Reader of the program's output and an io.Writer to its
+// input, so it can be plugged into pipes, scripts or a terminal.
+type ASCII struct {
+	c      *Computer
+	buf    []byte
+	values []int64
+	// midLine is set when the last character out wasn't a newline, and
+	// valueLine while a passed through value's line is still open
+	midLine   bool
+	valueLine bool
+}
+
+// NewASCII returns an ASCII adapter for c.
+func NewASCII(c *Computer) *ASCII {
+	return &ASCII{c: c}
+}
+
+// Values returns every non-ASCII value the program has output so far,
+// leaving out any beyond int64.
+func (a *ASCII) Values() []int64 {
+	return a.values
+}
+
+// WriteLine queues s and a newline as input.
+func (a *ASCII) WriteLine(s string) {
+	for _, r := range s {
+		a.c.AddInput(int64(r))
+	}
+	a.c.AddInput('\n')
+}
+
+// Write queues p as input, one value per byte.
+func (a *ASCII) Write(p []byte) (int, error) {
+	for _, b := range p {
+		a.c.AddInput(int64(b))
+	}
+	return len(p), nil
+}
+
+// Read reads the program's output, running it as needed. It returns io.EOF
+// once the program has halted and ErrAwaitingInput when it is blocked.
+func (a *ASCII) Read(p []byte) (int, error) {
+	if len(a.buf) == 0 {
+		if err := a.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, a.buf)
+	a.buf = a.buf[n:]
+	return n, nil
+}
+
+// ReadLine returns the next line of output without its newline. If the
+// program halts or blocks part way through a line the partial line is
+// returned; with nothing at all it returns io.EOF or ErrAwaitingInput.
+func (a *ASCII) ReadLine() (string, error) {
+	for {
+		if i := bytes.IndexByte(a.buf, '\n'); i >= 0 {
+			line := string(a.buf[:i])
+			a.buf = a.buf[i+1:]
+			return line, nil
+		}
+		if err := a.fill(); err != nil {
+			if len(a.buf) > 0 {
+				line := string(a.buf)
+				a.buf = nil
+				return line, nil
+			}
+			return "", err
+		}
+	}
+}
+
+// ReadUntilPrompt returns all output up to and including prompt. If the
+// program halts or blocks first, the text so far is returned along with
+// io.EOF or ErrAwaitingInput.
+func (a *ASCII) ReadUntilPrompt(prompt string) (string, error) {
+	for {
+		if i := bytes.Index(a.buf, []byte(prompt)); i >= 0 {
+			text := string(a.buf[:i+len(prompt)])
+			a.buf = a.buf[i+len(prompt):]
+			return text, nil
+		}
+		if err := a.fill(); err != nil {
+			text := string(a.buf)
+			a.buf = nil
+			return text, err
+		}
+	}
+}
+
+// Interact connects the program to a terminal or pipe: output is copied to
+// out, and whenever the program wants input a line is read from in. It
+// returns nil once the program halts, or ErrAwaitingInput if in runs dry
+// first.
+func (a *ASCII) Interact(in io.Reader, out io.Writer) error {
+	lines := bufio.NewReader(in)
+	for {
+		_, err := io.Copy(out, a)
+		switch {
+		case err == nil:
+			// io.Copy treats EOF as success, so the program has halted
+			return nil
+		case err != ErrAwaitingInput:
+			return err
+		}
+		line, err := lines.ReadString('\n')
+		if line == "" && err != nil {
+			return ErrAwaitingInput
+		}
+		if line[len(line)-1] != '\n' {
+			line += "\n"
+		}
+		a.Write([]byte(line))
+	}
+}
+
+// endValue ends the line of a passed through value when the program
+// doesn't, reporting whether there was one to end.
+func (a *ASCII) endValue() bool {
+	if !a.valueLine {
+		return false
+	}
+	a.buf = append(a.buf, '\n')
+	a.midLine, a.valueLine = false, false
+	return true
+}
+
+// fill runs the program until it outputs and appends what it said to buf.
+func (a *ASCII) fill() error {
+	// a value's line is ended before reporting the program stopped
+	stopped := func(err error) error {
+		if a.endValue() {
+			return nil
+		}
+		return err
+	}
+	if a.c.finished && len(a.c.outputs) == 0 {
+		return stopped(io.EOF)
+	}
+	if a.c.inputBlocked && len(a.c.inputs) == 0 && len(a.c.outputs) == 0 {
+		return stopped(ErrAwaitingInput)
+	}
+	if err := a.c.RunUntilOutput(1); err != nil {
+		return err
+	}
+	if len(a.c.outputs) == 0 {
+		if a.c.finished {
+			return stopped(io.EOF)
+		}
+		return stopped(ErrAwaitingInput)
+	}
+	for len(a.c.outputs) > 0 {
+		v := a.c.PopBigOutput()
+		if !v.IsInt64() || v.Int64() < 0 || v.Int64() > 127 {
+			if v.IsInt64() {
+				a.values = append(a.values, v.Int64())
+			}
+			if a.midLine {
+				a.buf = append(a.buf, '\n')
+			}
+			// the newline after it is left to the program, which usually
+			// prints one anyway
+			a.buf = v.Append(a.buf, 10)
+			a.midLine, a.valueLine = true, true
+			continue
+		}
+		if v.Int64() != '\n' {
+			a.endValue()
+		}
+		a.valueLine = false
+		a.buf = append(a.buf, byte(v.Int64()))
+		a.midLine = v.Int64() != '\n'
+	}
+	return nil
+}
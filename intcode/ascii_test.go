@@ -0,0 +1,60 @@
+package intcode
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+// printer returns a program that outputs each of outputs and halts.
+func printer(outputs ...int64) []int64 {
+	program := []int64{}
+	for _, v := range outputs {
+		program = append(program, 104, v)
+	}
+	return append(program, 99)
+}
+
+func TestASCIIValueLines(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		outputs []int64
+		lines   []string
+	}{
+		{"newline after value", []int64{'H', 'i', '\n', 1000, '\n'}, []string{"Hi", "1000"}},
+		{"value mid-line", []int64{'H', 'i', 1000, '\n'}, []string{"Hi", "1000"}},
+		{"no newline after value", []int64{'H', 'i', '\n', 1000}, []string{"Hi", "1000"}},
+		{"text after value", []int64{1000, 'o', 'k', '\n'}, []string{"1000", "ok"}},
+		{"two values", []int64{1000, 2000, '\n', '\n'}, []string{"1000", "2000", ""}},
+	} {
+		a := NewASCII(NewComputer(printer(tc.outputs...)))
+		lines := []string{}
+		for {
+			line, err := a.ReadLine()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			lines = append(lines, line)
+		}
+		if !reflect.DeepEqual(lines, tc.lines) {
+			t.Errorf("%s: got %q, want %q", tc.name, lines, tc.lines)
+		}
+	}
+}
+
+func TestASCIIValueBeforeInput(t *testing.T) {
+	// prints 1000 and waits for input
+	a := NewASCII(NewComputer([]int64{104, 1000, 3, 0, 99}))
+	if line, err := a.ReadLine(); line != "1000" || err != nil {
+		t.Errorf("got %q, %v, want 1000", line, err)
+	}
+	if line, err := a.ReadLine(); err != ErrAwaitingInput {
+		t.Errorf("got %q, %v, want ErrAwaitingInput", line, err)
+	}
+	if !reflect.DeepEqual(a.Values(), []int64{1000}) {
+		t.Errorf("values %v", a.Values())
+	}
+}
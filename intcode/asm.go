@@ -0,0 +1,260 @@
+package intcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AsmError reports a problem in assembly source along with its line number.
+type AsmError struct {
+	Line int
+	Msg  string
+}
+
+func (e *AsmError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// asmStatement is one instruction or .data directive waiting for its labels
+// to be resolved.
+type asmStatement struct {
+	line     int
+	addr     int64
+	op       OpCode
+	operands []string
+	data     bool
+}
+
+// Assemble turns assembly source into an Intcode program. The format is one
+// statement per line:
+//
+//	; comments run to the end of the line
+//	loop:   ADDITION [count], #-1, [count]
+//	        JIT [count], #loop
+//	        OUTPUT rb+2
+//	        QUIT
+//	count:  .data 10
+//	msg:    .data "hello\n", 0
+//
+// Mnemonics are the OpCode names. Operands are [x] for position mode, #x for
+// immediate and rb+x or rb-x for relative, where x is a number, a label or
+// label+n / label-n. .data emits numbers, label addresses or the characters
+// of a quoted string.
+func Assemble(src string) ([]int64, error) {
+	labels := make(map[string]int64)
+	statements := []asmStatement{}
+	addr := int64(0)
+
+	for n, line := range strings.Split(src, "\n") {
+		lineNo := n + 1
+		line = strings.TrimSpace(stripComment(line))
+
+		// any number of labels may prefix a statement
+		for {
+			i := strings.Index(line, ":")
+			if i < 0 || !isIdent(line[:i]) {
+				break
+			}
+			name := line[:i]
+			if _, ok := labels[name]; ok {
+				return nil, &AsmError{lineNo, "duplicate label " + name}
+			}
+			labels[name] = addr
+			line = strings.TrimSpace(line[i+1:])
+		}
+		if line == "" {
+			continue
+		}
+
+		mnemonic, rest := line, ""
+		if i := strings.IndexFunc(line, unicode.IsSpace); i >= 0 {
+			mnemonic, rest = line[:i], strings.TrimSpace(line[i:])
+		}
+		operands, err := splitOperands(rest)
+		if err != nil {
+			return nil, &AsmError{lineNo, err.Error()}
+		}
+
+		if mnemonic == ".data" {
+			size := int64(0)
+			for _, o := range operands {
+				if strings.HasPrefix(o, "\"") {
+					s, err := strconv.Unquote(o)
+					if err != nil {
+						return nil, &AsmError{lineNo, "bad string " + o}
+					}
+					size += int64(len([]rune(s)))
+				} else {
+					size++
+				}
+			}
+			statements = append(statements, asmStatement{lineNo, addr, 0, operands, true})
+			addr += size
+			continue
+		}
+
+		op, ok := LookupOpCode(strings.ToUpper(mnemonic))
+		if !ok {
+			return nil, &AsmError{lineNo, "unknown mnemonic " + mnemonic}
+		}
+		if len(operands) != op.Params() {
+			return nil, &AsmError{lineNo, fmt.Sprintf("%v takes %d operands, got %d", op, op.Params(), len(operands))}
+		}
+		statements = append(statements, asmStatement{lineNo, addr, op, operands, false})
+		addr += int64(1 + op.Params())
+	}
+
+	program := make([]int64, 0, addr)
+	for _, s := range statements {
+		if s.data {
+			for _, o := range s.operands {
+				if strings.HasPrefix(o, "\"") {
+					str, _ := strconv.Unquote(o)
+					for _, r := range str {
+						program = append(program, int64(r))
+					}
+					continue
+				}
+				v, err := evalOperand(o, labels)
+				if err != nil {
+					return nil, &AsmError{s.line, err.Error()}
+				}
+				program = append(program, v)
+			}
+			continue
+		}
+
+		word := int64(s.op)
+		params := make([]int64, len(s.operands))
+		scale := int64(100)
+		for i, o := range s.operands {
+			mode, value, err := parseOperand(o, labels)
+			if err != nil {
+				return nil, &AsmError{s.line, err.Error()}
+			}
+			if mode == IMMEDIATE && s.op.Writes() == i+1 {
+				return nil, &AsmError{s.line, "cannot write to immediate operand " + o}
+			}
+			word += int64(mode) * scale
+			scale *= 10
+			params[i] = value
+		}
+		program = append(program, word)
+		program = append(program, params...)
+	}
+	return program, nil
+}
+
+// FormatProgram renders program in the comma separated form the days load.
+func FormatProgram(program []int64) string {
+	return joinWords(program, ",")
+}
+
+func stripComment(line string) string {
+	quoted, escaped := false, false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitOperands splits on commas that aren't inside a quoted string.
+func splitOperands(s string) ([]string, error) {
+	operands := []string{}
+	if s == "" {
+		return operands, nil
+	}
+	quoted, escaped := false, false
+	start := 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			operands = append(operands, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	operands = append(operands, strings.TrimSpace(s[start:]))
+	for _, o := range operands {
+		if o == "" {
+			return nil, fmt.Errorf("empty operand")
+		}
+	}
+	return operands, nil
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseOperand(o string, labels map[string]int64) (ParameterMode, int64, error) {
+	switch {
+	case strings.HasPrefix(o, "#"):
+		v, err := evalOperand(o[1:], labels)
+		return IMMEDIATE, v, err
+	case strings.HasPrefix(o, "[") && strings.HasSuffix(o, "]"):
+		v, err := evalOperand(o[1:len(o)-1], labels)
+		return POSITION, v, err
+	case o == "rb":
+		return RELATIVE, 0, nil
+	case strings.HasPrefix(o, "rb+"):
+		v, err := evalOperand(o[3:], labels)
+		return RELATIVE, v, err
+	case strings.HasPrefix(o, "rb-"):
+		v, err := evalOperand(o[3:], labels)
+		return RELATIVE, -v, err
+	default:
+		return POSITION, 0, fmt.Errorf("bad operand %q, want [x], #x or rb+x", o)
+	}
+}
+
+// evalOperand resolves a number, a label or label+n / label-n.
+func evalOperand(e string, labels map[string]int64) (int64, error) {
+	e = strings.TrimSpace(e)
+	if v, err := strconv.ParseInt(e, 10, 64); err == nil {
+		return v, nil
+	}
+	name, offset := e, int64(0)
+	if i := strings.LastIndexAny(e, "+-"); i > 0 {
+		o, err := strconv.ParseInt(strings.TrimSpace(e[i+1:]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad offset in %q", e)
+		}
+		if e[i] == '-' {
+			o = -o
+		}
+		name, offset = strings.TrimSpace(e[:i]), o
+	}
+	addr, ok := labels[name]
+	if !ok {
+		return 0, fmt.Errorf("undefined label %q", name)
+	}
+	return addr + offset, nil
+}
@@ -0,0 +1,79 @@
+package intcode
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// disassembly renders program the way the disasm tool does, minus the
+// addresses, so Assemble can read it back.
+func disassembly(program []int64) string {
+	lines := []string{}
+	for _, l := range Disassemble(program) {
+		lines = append(lines, l.Text())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func words(s string) []int64 {
+	w := []int64{}
+	for _, r := range s {
+		w = append(w, int64(r))
+	}
+	return w
+}
+
+func TestAssembleStrings(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want []int64
+	}{
+		{`.data "\\", 1`, []int64{'\\', 1}},
+		{`.data "a\"b", 2 ; "comment`, []int64{'a', '"', 'b', 2}},
+		{`.data "x\\\"y;z\\"`, words(`x\"y;z\`)},
+		{`.data ";,", "\\\\"`, words(`;,\\`)},
+	} {
+		got, err := Assemble(tc.src)
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestAssembleRoundTrip(t *testing.T) {
+	programs := [][]int64{
+		countdown(10),
+		// a quine
+		{109, 1, 204, -1, 1001, 100, 1, 100, 1008, 100, 16, 101, 1006, 101, 0, 99},
+		append([]int64{4, 5, 99, 0, 0}, words("a\\\"b\\\\c\n; d, \"e\"")...),
+		append([]int64{99}, words(`\\\\`)...),
+	}
+	rng := rand.New(rand.NewSource(6))
+	data := make([]byte, 128)
+	for i := 0; i < 500; i++ {
+		rng.Read(data)
+		program, _ := genProgram(data)
+		programs = append(programs, program)
+	}
+
+	for _, program := range programs {
+		if got, err := Assemble(".data " + FormatProgram(program)); err != nil || !reflect.DeepEqual(got, program) {
+			t.Errorf("%v: FormatProgram assembles to %v, %v", program, got, err)
+		}
+		src := disassembly(program)
+		got, err := Assemble(src)
+		if err != nil {
+			t.Errorf("%v: %v\n%s", program, err, src)
+			continue
+		}
+		if !reflect.DeepEqual(got, program) {
+			t.Errorf("%v: assembles back to %v\n%s", program, got, src)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package intcode
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// countdown loops n times before outputting 0, so the benchmarks have a
+// program to run even without a puzzle input on disk.
+func countdown(n int64) []int64 {
+	p := []int64{
+		1101, n, 0, 100, // mem[100] = n
+		1001, 100, -1, 100, // mem[100] -= 1
+		1005, 100, 4, // loop while mem[100] != 0
+		4, 100,
+		99,
+	}
+	return append(p, make([]int64, 100)...)
+}
+
+func loadBoost(b *testing.B) []int64 {
+	bd, err := ioutil.ReadFile("../day9/input.txt")
+	if err != nil {
+		b.Skip("day9 input.txt not available")
+	}
+	sProg := strings.Split(strings.TrimSpace(string(bd)), ",")
+	program := make([]int64, len(sProg))
+	for i, v := range sProg {
+		program[i], _ = strconv.ParseInt(v, 10, 64)
+	}
+	return program
+}
+
+func runComputer(b *testing.B, program []int64, uncached bool, inputs ...int64) []int64 {
+	c := NewComputer(program, inputs...)
+	c.uncached = uncached
+	if err := c.Run(); err != nil {
+		b.Fatal(err)
+	}
+	outputs := []int64{}
+	for c.PendingOutputs() > 0 {
+		outputs = append(outputs, c.PopOutput())
+	}
+	return outputs
+}
+
+func benchmarkProgram(b *testing.B, program []int64, impl string, inputs ...int64) {
+	want := legacyRun(program, inputs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var got []int64
+		switch impl {
+		case "legacy":
+			got = legacyRun(program, inputs)
+		case "uncached":
+			got = runComputer(b, program, true, inputs...)
+		default:
+			got = runComputer(b, program, false, inputs...)
+		}
+		if len(got) != len(want) || got[len(got)-1] != want[len(want)-1] {
+			b.Fatalf("outputs %v differ from legacy %v", got, want)
+		}
+	}
+}
+
+func BenchmarkBoostLegacy(b *testing.B)   { benchmarkProgram(b, loadBoost(b), "legacy", 2) }
+func BenchmarkBoostUncached(b *testing.B) { benchmarkProgram(b, loadBoost(b), "uncached", 2) }
+func BenchmarkBoost(b *testing.B)         { benchmarkProgram(b, loadBoost(b), "cached", 2) }
+
+func BenchmarkCountdownLegacy(b *testing.B)   { benchmarkProgram(b, countdown(100000), "legacy") }
+func BenchmarkCountdownUncached(b *testing.B) { benchmarkProgram(b, countdown(100000), "uncached") }
+func BenchmarkCountdown(b *testing.B)         { benchmarkProgram(b, countdown(100000), "cached") }
+
+// the day19 pattern: lots of short-lived machines
+func BenchmarkFreshLegacy(b *testing.B) { benchmarkProgram(b, countdown(10), "legacy") }
+func BenchmarkFresh(b *testing.B)       { benchmarkProgram(b, countdown(10), "cached") }
+
+var decodeWords = []int64{1, 2, 3, 4, 99, 1002, 1101, 109, 204, 21101, 1206, 22201, 2105, 1008}
+
+func BenchmarkDecodeLegacy(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		w := decodeWords[i%len(decodeWords)]
+		legacyParseOpCode(w)
+		legacyParseModes(w)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		decode(decodeWords[i%len(decodeWords)])
+	}
+}
+
+// What follows is a condensed copy of the strconv based VM the days carried
+// before this package existed, kept as the baseline for the benchmarks above.
+
+type legacyComputer struct {
+	program      []int64
+	inputs       []int64
+	relativeBase int64
+	pos          int64
+	finished     bool
+	outputs      []int64
+}
+
+func legacyRun(program []int64, inputs []int64) []int64 {
+	p := make([]int64, len(program))
+	copy(p, program)
+	p = append(p, make([]int64, 10000)...)
+	c := legacyComputer{p, inputs, 0, 0, false, []int64{}}
+	for !c.finished {
+		legacyCycle(&c)
+	}
+	return c.outputs
+}
+
+func legacyParseOpCode(i int64) OpCode {
+	s := strconv.Itoa(int(i))
+	if len(s) > 1 {
+		r, _ := strconv.Atoi(s[len(s)-2:])
+		return OpCode(r)
+	} else {
+		r, _ := strconv.Atoi(s)
+		return OpCode(r)
+	}
+}
+
+func legacyParseModes(i int64) []ParameterMode {
+	s := strconv.Itoa(int(i))
+	rl := 3
+	if len(s)-3 > 3 {
+		rl = len(s) - 3
+	}
+	r := make([]ParameterMode, rl)
+	ptr := 0
+	for i := len(s) - 3; i >= 0; i-- {
+		if s[i] == '1' {
+			r[ptr] = IMMEDIATE
+		} else if s[i] == '2' {
+			r[ptr] = RELATIVE
+		}
+		ptr += 1
+	}
+	return r
+}
+
+func legacyGet(c *legacyComputer, mode ParameterMode, value int64, read bool) int64 {
+	if mode == POSITION {
+		if read {
+			return c.program[value]
+		}
+		return value
+	} else if mode == IMMEDIATE {
+		return value
+	}
+	if read {
+		return c.program[c.relativeBase+value]
+	}
+	return c.relativeBase + value
+}
+
+func legacyCycle(c *legacyComputer) {
+	op := legacyParseOpCode(c.program[c.pos])
+	modes := legacyParseModes(c.program[c.pos])
+
+	switch op {
+	case ADDITION, MULTIPLY, LT, EQ:
+		value1 := legacyGet(c, modes[0], c.program[c.pos+1], true)
+		value2 := legacyGet(c, modes[1], c.program[c.pos+2], true)
+		posDest := legacyGet(c, modes[2], c.program[c.pos+3], false)
+		switch op {
+		case ADDITION:
+			c.program[posDest] = value1 + value2
+		case MULTIPLY:
+			c.program[posDest] = value1 * value2
+		case LT:
+			c.program[posDest] = 0
+			if value1 < value2 {
+				c.program[posDest] = 1
+			}
+		case EQ:
+			c.program[posDest] = 0
+			if value1 == value2 {
+				c.program[posDest] = 1
+			}
+		}
+		c.pos += 4
+	case STORE:
+		posDest := legacyGet(c, modes[0], c.program[c.pos+1], false)
+		c.program[posDest] = c.inputs[0]
+		c.inputs = c.inputs[1:]
+		c.pos += 2
+	case OUTPUT:
+		c.outputs = append(c.outputs, legacyGet(c, modes[0], c.program[c.pos+1], true))
+		c.pos += 2
+	case JIT, JIF:
+		value1 := legacyGet(c, modes[0], c.program[c.pos+1], true)
+		value2 := legacyGet(c, modes[1], c.program[c.pos+2], true)
+		if (op == JIT) == (value1 != 0) {
+			c.pos = value2
+		} else {
+			c.pos += 3
+		}
+	case RBO:
+		c.relativeBase += legacyGet(c, modes[0], c.program[c.pos+1], true)
+		c.pos += 2
+	case QUIT:
+		c.finished = true
+	default:
+		panic("unknown op code")
+	}
+}
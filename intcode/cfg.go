@@ -0,0 +1,209 @@
+package intcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type EdgeKind int
+
+const (
+	// FALLTHROUGH continues to the next instruction.
+	FALLTHROUGH EdgeKind = iota
+	// JUMP is a JIT or JIF to an immediate target.
+	JUMP
+	// INDIRECT is a jump whose target is only known at run time, usually a
+	// subroutine return.
+	INDIRECT
+	// RETURN points at a return address the block pushes before a call.
+	RETURN
+)
+
+func (k EdgeKind) String() string {
+	switch k {
+	case FALLTHROUGH:
+		return "FALLTHROUGH"
+	case JUMP:
+		return "JUMP"
+	case INDIRECT:
+		return "INDIRECT"
+	case RETURN:
+		return "RETURN"
+	}
+	return "UNKNOWN"
+}
+
+// Edge leaves a block for the block starting at To, or for somewhere
+// unknown when To is -1: an INDIRECT jump, or a target that isn't the start
+// of any block, such as a jump outside the program or into the middle of an
+// instruction.
+type Edge struct {
+	To   int64
+	Kind EdgeKind
+}
+
+// Block is a basic block: a straight run of instructions that is only ever
+// entered at the top and left at the bottom.
+type Block struct {
+	Start int64
+	Lines []Line
+	Edges []Edge
+}
+
+// End returns the address just past the block.
+func (b *Block) End() int64 {
+	last := b.Lines[len(b.Lines)-1]
+	return last.Addr + int64(len(last.Words))
+}
+
+// returnAddress reports the code address an ADDITION pushes as a return
+// address, using the same heuristic as ReachableCode.
+func returnAddress(l Line) (int64, bool) {
+	if l.Op != ADDITION || l.Modes[0] != IMMEDIATE || l.Modes[1] != IMMEDIATE || l.Modes[2] != RELATIVE {
+		return 0, false
+	}
+	if l.Words[1] != 0 && l.Words[2] != 0 {
+		return 0, false
+	}
+	return l.Words[1] + l.Words[2], true
+}
+
+// branches reports whether a JIT or JIF line can take its jump and whether
+// it can fall through, resolving immediate conditions.
+func branches(l Line) (taken bool, falls bool) {
+	if l.Modes[0] != IMMEDIATE {
+		return true, true
+	}
+	jumps := (l.Words[1] != 0) == (l.Op == JIT)
+	return jumps, !jumps
+}
+
+// ControlFlow splits the code reachable in program into basic blocks, in
+// address order, with the edges between them.
+func ControlFlow(program []int64) []*Block {
+	code := []Line{}
+	for _, l := range Disassemble(program) {
+		if !l.Data {
+			code = append(code, l)
+		}
+	}
+
+	// a block starts wherever control can arrive other than by falling
+	// through from the instruction before
+	targets := make(map[int64]bool)
+	for _, l := range code {
+		if (l.Op == JIT || l.Op == JIF) && l.Modes[1] == IMMEDIATE {
+			targets[l.Words[2]] = true
+		}
+		if ret, ok := returnAddress(l); ok {
+			targets[ret] = true
+		}
+	}
+
+	blocks := []*Block{}
+	var b *Block
+	for i, l := range code {
+		leader := i == 0 || targets[l.Addr]
+		if i > 0 {
+			prev := code[i-1]
+			leader = leader || prev.Addr+int64(len(prev.Words)) != l.Addr ||
+				prev.Op == JIT || prev.Op == JIF || prev.Op == QUIT
+		}
+		if leader {
+			b = &Block{Start: l.Addr}
+			blocks = append(blocks, b)
+		}
+		b.Lines = append(b.Lines, l)
+	}
+
+	starts := make(map[int64]bool)
+	for _, b := range blocks {
+		starts[b.Start] = true
+	}
+	edge := func(to int64, kind EdgeKind) Edge {
+		if !starts[to] {
+			to = -1
+		}
+		return Edge{to, kind}
+	}
+	for _, b := range blocks {
+		for _, l := range b.Lines {
+			if ret, ok := returnAddress(l); ok {
+				b.Edges = append(b.Edges, edge(ret, RETURN))
+			}
+		}
+		last := b.Lines[len(b.Lines)-1]
+		switch last.Op {
+		case QUIT:
+		case JIT, JIF:
+			taken, falls := branches(last)
+			if taken && last.Modes[1] == IMMEDIATE {
+				b.Edges = append(b.Edges, edge(last.Words[2], JUMP))
+			} else if taken {
+				b.Edges = append(b.Edges, Edge{-1, INDIRECT})
+			}
+			if falls {
+				b.Edges = append(b.Edges, edge(b.End(), FALLTHROUGH))
+			}
+		default:
+			if starts[b.End()] {
+				b.Edges = append(b.Edges, Edge{b.End(), FALLTHROUGH})
+			}
+		}
+	}
+	return blocks
+}
+
+// dotEscape quotes s for use inside a DOT string.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// WriteDot writes blocks as a Graphviz digraph, one box per block listing
+// its instructions. Jumps are blue, return addresses dotted, indirect jumps
+// lead to a shared "indirect" node and edges to anywhere that isn't a block
+// to a shared "unknown" node.
+func WriteDot(w io.Writer, blocks []*Block) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph intcode {")
+	fmt.Fprintln(bw, `	node [shape=box fontname="monospace"];`)
+	indirect, unknown := false, false
+	for _, b := range blocks {
+		text := []string{}
+		for _, l := range b.Lines {
+			text = append(text, dotEscape(fmt.Sprintf("%d: %s", l.Addr, l.Text())))
+		}
+		// \l ends each line left aligned
+		fmt.Fprintf(bw, "\tb%d [label=\"%s\\l\"];\n", b.Start, strings.Join(text, `\l`))
+	}
+	for _, b := range blocks {
+		for _, e := range b.Edges {
+			to := fmt.Sprintf("b%d", e.To)
+			if e.To < 0 && e.Kind != INDIRECT {
+				unknown = true
+				to = "unknown"
+			}
+			switch e.Kind {
+			case FALLTHROUGH:
+				fmt.Fprintf(bw, "\tb%d -> %s;\n", b.Start, to)
+			case JUMP:
+				fmt.Fprintf(bw, "\tb%d -> %s [color=blue];\n", b.Start, to)
+			case RETURN:
+				fmt.Fprintf(bw, "\tb%d -> %s [style=dotted];\n", b.Start, to)
+			case INDIRECT:
+				indirect = true
+				fmt.Fprintf(bw, "\tb%d -> indirect [color=red];\n", b.Start)
+			}
+		}
+	}
+	if indirect {
+		fmt.Fprintln(bw, `	indirect [shape=ellipse label="indirect"];`)
+	}
+	if unknown {
+		fmt.Fprintln(bw, `	unknown [shape=ellipse label="unknown target"];`)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
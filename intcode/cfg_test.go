@@ -0,0 +1,56 @@
+package intcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestControlFlowUnknownTargets(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		program []int64
+		edges   []Edge
+		dot     []string
+	}{
+		{"jump to a block", []int64{1105, 1, 4, 99, 99}, []Edge{{4, JUMP}},
+			[]string{"b0 -> b4 [color=blue];"}},
+		{"jump outside the program", []int64{1105, 1, -5}, []Edge{{-1, JUMP}},
+			[]string{"b0 -> unknown [color=blue];", `unknown [shape=ellipse label="unknown target"];`}},
+		{"jump into an instruction", []int64{1105, 1, 1}, []Edge{{-1, JUMP}},
+			[]string{"b0 -> unknown [color=blue];"}},
+		{"indirect jump", []int64{5, 3, 3, 99}, []Edge{{-1, INDIRECT}, {3, FALLTHROUGH}},
+			[]string{"b0 -> indirect [color=red];", "b0 -> b3;"}},
+	} {
+		blocks := ControlFlow(tc.program)
+		if len(blocks) == 0 {
+			t.Errorf("%s: no blocks", tc.name)
+			continue
+		}
+		got := blocks[0].Edges
+		if len(got) != len(tc.edges) {
+			t.Errorf("%s: edges %v, want %v", tc.name, got, tc.edges)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.edges[i] {
+				t.Errorf("%s: edges %v, want %v", tc.name, got, tc.edges)
+				break
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := WriteDot(&buf, blocks); err != nil {
+			t.Fatal(err)
+		}
+		dot := buf.String()
+		for _, want := range tc.dot {
+			if !strings.Contains(dot, want) {
+				t.Errorf("%s: dot is missing %q:\n%s", tc.name, want, dot)
+			}
+		}
+		if strings.Contains(dot, "b-") {
+			t.Errorf("%s: dot has a negative block id:\n%s", tc.name, dot)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: ascii [-program file]
+//
+// Runs a text based program with its output on stdout and a line of stdin
+// read whenever it asks for input, so it can be played from a terminal or
+// fed a script through a pipe.
+func main() {
+
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+
+	a := intcode.NewASCII(intcode.NewComputer(program))
+	if err := a.Interact(os.Stdin, os.Stdout); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: asm [source file, defaults to stdin] > program.txt
+func main() {
+
+	var src []byte
+	var err error
+	if len(os.Args) > 1 {
+		src, err = ioutil.ReadFile(os.Args[1])
+	} else {
+		src, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	program, err := intcode.Assemble(string(src))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(intcode.FormatProgram(program))
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: cfg [-program file] > program.dot
+//
+// Writes the control flow graph of the program's reachable code in
+// Graphviz format, e.g. for dot -Tsvg program.dot > program.svg.
+func main() {
+
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+
+	if err := intcode.WriteDot(os.Stdout, intcode.ControlFlow(program)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: cover [-input 1,2] [-ascii] [-run=false] [-merge a.cov,b.cov] [-o out.cov] [-program file]
+//
+// Runs the program, feeding it the -input values or with -ascii stdin as
+// ASCII lines, and prints its disassembly annotated with how often each
+// instruction ran. Coverage saved with -o from several runs can be combined
+// with -merge, and -run=false reports on the merged files alone.
+func main() {
+
+	inputs := flag.String("input", "", "comma separated input values")
+	ascii := flag.Bool("ascii", false, "feed stdin to the program as ASCII")
+	run := flag.Bool("run", true, "run the program, otherwise only report merged coverage")
+	merge := flag.String("merge", "", "comma separated coverage files to merge in")
+	out := flag.String("o", "", "save the combined coverage to this file")
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+	values, err := intcode.ParseInputs(*inputs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cv := intcode.NewCoverage()
+	if *merge != "" {
+		for _, file := range strings.Split(*merge, ",") {
+			f, err := os.Open(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			other, err := intcode.DecodeCoverage(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+				os.Exit(1)
+			}
+			cv.Merge(other)
+		}
+	}
+
+	if *run {
+		c := intcode.NewComputer(program, values...)
+		c.SetCoverage(cv)
+		if *ascii {
+			err = intcode.NewASCII(c).Interact(os.Stdin, os.Stderr)
+		} else {
+			err = c.Run()
+		}
+		if err != nil && err != intcode.ErrAwaitingInput {
+			fmt.Fprintln(os.Stderr, err)
+		} else if !c.Finished() {
+			fmt.Fprintln(os.Stderr, "program is waiting for more input")
+		}
+	}
+
+	if err := cv.WriteAnnotated(os.Stdout, program); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		err = cv.Encode(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
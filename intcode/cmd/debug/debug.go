@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: debug [-program file]
+func main() {
+
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+
+	d := intcode.NewDebugger(intcode.NewComputer(program), os.Stdout)
+	d.Repl(os.Stdin)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: disasm [-program file]
+func main() {
+
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+
+	lines := intcode.Disassemble(program)
+	labels := make(map[int64]bool)
+	for _, l := range intcode.Labels(lines) {
+		labels[l] = true
+	}
+
+	for _, l := range lines {
+		if labels[l.Addr] {
+			fmt.Printf("L%d:\n", l.Addr)
+		}
+		fmt.Println(l)
+	}
+}
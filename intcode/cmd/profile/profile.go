@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: profile [-input 1,2] [-ascii] [-top n] [-heatmap file.csv] [-pprof file.pb.gz] [-program file]
+//
+// Runs the program to completion, feeding it the -input values, and prints
+// a report of where it spent its time. With -ascii stdin is fed to the
+// program as ASCII lines, for the text adventure style days, and its output
+// is copied to stderr.
+func main() {
+
+	inputs := flag.String("input", "", "comma separated input values")
+	ascii := flag.Bool("ascii", false, "feed stdin to the program as ASCII")
+	top := flag.Int("top", 20, "rows per report section, 0 for all")
+	heatmap := flag.String("heatmap", "", "write memory access counts as CSV to this file")
+	pprof := flag.String("pprof", "", "write a pprof profile to this file")
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+	values, err := intcode.ParseInputs(*inputs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	c := intcode.NewComputer(program, values...)
+	p := intcode.NewProfiler()
+	c.SetProfiler(p)
+
+	if *ascii {
+		err = intcode.NewASCII(c).Interact(os.Stdin, os.Stderr)
+	} else {
+		err = c.Run()
+	}
+	if err != nil && err != intcode.ErrAwaitingInput {
+		fmt.Fprintln(os.Stderr, err)
+	} else if !c.Finished() {
+		fmt.Fprintln(os.Stderr, "program is waiting for more input")
+	}
+
+	if err := p.WriteReport(os.Stdout, *top); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *heatmap != "" {
+		if err := writeFile(*heatmap, p.WriteHeatmap); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *pprof != "" {
+		if err := writeFile(*pprof, p.WritePprof); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
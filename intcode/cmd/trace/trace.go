@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: trace [-input 1,2] [-from a] [-to b] [-ops ADDITION,OUTPUT] [-program file]
+//
+// Runs the program to completion, feeding it the -input values, and writes
+// one JSON record per executed instruction to stdout.
+func main() {
+
+	inputs := flag.String("input", "", "comma separated input values")
+	from := flag.Int64("from", 0, "lowest address to trace")
+	to := flag.Int64("to", -1, "highest address to trace, -1 for no limit")
+	ops := flag.String("ops", "", "comma separated mnemonics to trace, default all")
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+	values, err := intcode.ParseInputs(*inputs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	c := intcode.NewComputer(program, values...)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	t := intcode.NewTracer(w)
+	t.FilterAddr(*from, *to)
+	if *ops != "" {
+		filter := []intcode.OpCode{}
+		for _, name := range strings.Split(*ops, ",") {
+			op, ok := intcode.LookupOpCode(strings.ToUpper(strings.TrimSpace(name)))
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown opcode %q\n", name)
+				os.Exit(1)
+			}
+			filter = append(filter, op)
+		}
+		t.FilterOps(filter...)
+	}
+	c.SetTracer(t)
+
+	if err := c.Run(); err != nil {
+		w.Flush()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if !c.Finished() {
+		fmt.Fprintln(os.Stderr, "program is waiting for more input")
+	}
+	if t.Err() != nil {
+		fmt.Fprintln(os.Stderr, t.Err())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/mrbarge/aoc2019/intcode"
+)
+
+// Usage: transpile [-pkg main] [-o file.go] [-program file]
+//
+// Writes Go source implementing the program. The default package main
+// builds into a standalone binary that reads inputs from stdin and prints
+// outputs, which also makes it a handy cross-check against the VM.
+func main() {
+
+	pkg := flag.String("pkg", "main", "package name for the generated code")
+	out := flag.String("o", "", "file to write, default stdout")
+	programFile := intcode.ProgramFlag()
+	flag.Parse()
+	program := intcode.LoadProgram(*programFile)
+
+	src, err := intcode.Transpile(program, *pkg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := ioutil.WriteFile(*out, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,142 @@
+package intcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Coverage records how many times each address was executed as an
+// instruction. One Coverage can be attached to several computers in turn,
+// or separate ones merged, to see what a set of runs exercised together.
+type Coverage struct {
+	hits map[int64]int64
+}
+
+// NewCoverage returns an empty Coverage. Attach it with
+// Computer.SetCoverage.
+func NewCoverage() *Coverage {
+	return &Coverage{hits: make(map[int64]int64)}
+}
+
+// SetCoverage attaches cv to c, or stops recording when cv is nil.
+func (c *Computer) SetCoverage(cv *Coverage) {
+	c.coverage = cv
+}
+
+// Hits returns the number of times the instruction at addr was executed.
+func (cv *Coverage) Hits(addr int64) int64 {
+	return cv.hits[addr]
+}
+
+// Merge adds the counts from other into cv.
+func (cv *Coverage) Merge(other *Coverage) {
+	for addr, n := range other.hits {
+		cv.hits[addr] += n
+	}
+}
+
+// Summary returns how many of the code addresses in program were executed
+// and how many there are. Code is whatever ReachableCode finds plus
+// anything that was actually executed.
+func (cv *Coverage) Summary(program []int64) (covered int, total int) {
+	for _, l := range cv.lines(program) {
+		if !l.Data {
+			total++
+			if cv.hits[l.Addr] > 0 {
+				covered++
+			}
+		}
+	}
+	return covered, total
+}
+
+func (cv *Coverage) lines(program []int64) []Line {
+	code := ReachableCode(program)
+	for addr := range cv.hits {
+		if addr >= 0 && addr < int64(len(program)) {
+			if _, err := decode(program[addr]); err == nil {
+				code[addr] = true
+			}
+		}
+	}
+	return DisassembleWith(program, code)
+}
+
+// WriteAnnotated writes the disassembly of program with each instruction
+// prefixed by its execution count, or by ----- when it never ran, followed
+// by a summary line.
+func (cv *Coverage) WriteAnnotated(w io.Writer, program []int64) error {
+	bw := bufio.NewWriter(w)
+	covered, total := 0, 0
+	for _, l := range cv.lines(program) {
+		mark := ""
+		if !l.Data {
+			total++
+			mark = "-----"
+			if n := cv.hits[l.Addr]; n > 0 {
+				covered++
+				mark = strconv.FormatInt(n, 10)
+			}
+		}
+		fmt.Fprintf(bw, "%10s %s\n", mark, l)
+	}
+	percent := 0.0
+	if total > 0 {
+		percent = 100 * float64(covered) / float64(total)
+	}
+	fmt.Fprintf(bw, "covered %d of %d instructions (%.1f%%)\n", covered, total, percent)
+	return bw.Flush()
+}
+
+const coverageHeader = "intcode coverage"
+
+// Encode writes cv as text: a header line, then an address and count per
+// line in address order.
+func (cv *Coverage) Encode(w io.Writer) error {
+	addrs := make([]int64, 0, len(cv.hits))
+	for addr := range cv.hits {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, coverageHeader)
+	for _, addr := range addrs {
+		fmt.Fprintf(bw, "%d %d\n", addr, cv.hits[addr])
+	}
+	return bw.Flush()
+}
+
+// DecodeCoverage reads coverage written by Encode.
+func DecodeCoverage(r io.Reader) (*Coverage, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || scanner.Text() != coverageHeader {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("not an intcode coverage file")
+	}
+	cv := NewCoverage()
+	for n := 2; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("coverage line %d: want address and count", n)
+		}
+		addr, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("coverage line %d: %w", n, err)
+		}
+		hits, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("coverage line %d: %w", n, err)
+		}
+		cv.hits[addr] += hits
+	}
+	return cv, scanner.Err()
+}
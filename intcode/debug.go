@@ -0,0 +1,425 @@
+package intcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Debugger drives a Computer from text commands: breakpoints on addresses,
+// opcodes and I/O, memory watchpoints, stepping, inspection and patching.
+type Debugger struct {
+	c           *Computer
+	out         io.Writer
+	breakpoints map[int64]bool
+	opBreaks    map[OpCode]bool
+	watches     map[int64]bool
+	// watch hits from the instruction just executed
+	hits []string
+	// the undo log for stepping backwards, the number of outputs the steps
+	// in it produced and how many of those were trimmed off its start
+	history   []undo
+	produced  int64
+	dropped   int64
+	forgotten bool
+	write     *pendingWrite
+}
+
+// NewDebugger wraps c, writing everything it reports to out.
+func NewDebugger(c *Computer, out io.Writer) *Debugger {
+	d := &Debugger{
+		c:           c,
+		out:         out,
+		breakpoints: make(map[int64]bool),
+		opBreaks:    make(map[OpCode]bool),
+		watches:     make(map[int64]bool),
+	}
+	c.onWrite = func(addr int64, old int64, value int64) {
+		d.write = &pendingWrite{addr, old, c.bigs[addr]}
+		if d.watches[addr] {
+			d.hits = append(d.hits, fmt.Sprintf("watch [%d]: %d -> %d", addr, old, value))
+		}
+	}
+	return d
+}
+
+const debugHelp = `commands:
+  break <addr>          stop before executing addr (b)
+  break op <MNEMONIC>   stop before any instruction with that opcode
+  break input|output    stop before STORE / OUTPUT
+  delete <addr|op|input|output>
+  watch <addr>          stop after any write to addr (w)
+  unwatch <addr>
+  list                  show breakpoints and watchpoints
+  step [n]              execute n instructions, default 1 (s)
+  continue              run until a break, watch, halt or input block (c)
+  back [n]              step backwards n instructions, default 1 (bs)
+  rcontinue             run backwards to a breakpoint or the start (rc)
+  lastwrite <addr>      run backwards to the instruction that last wrote addr
+  before [n]            run backwards to before output n, default the latest
+  regs                  show pos, relative base and machine state (r)
+  dump <addr> [n]       print n words of memory, default 8 (x)
+  patch <addr> <v>...   write values starting at addr
+  disasm [addr] [n]     disassemble n instructions, default from pos (d)
+  input <v>...          queue input values (i)
+  line <text>           queue text as ASCII followed by a newline
+  output                pop and print pending outputs (o)
+  quit                  leave the debugger (q)`
+
+// Repl reads commands from in until quit or end of input.
+func (d *Debugger) Repl(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	d.printState()
+	for {
+		fmt.Fprint(d.out, "(icdb) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(d.out)
+			return
+		}
+		if quit := d.Exec(scanner.Text()); quit {
+			return
+		}
+	}
+}
+
+// Exec runs a single command line and reports whether it asked to quit.
+func (d *Debugger) Exec(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "help", "h", "?":
+		fmt.Fprintln(d.out, debugHelp)
+	case "quit", "q":
+		return true
+	case "break", "b":
+		d.setBreak(args, true)
+	case "delete":
+		d.setBreak(args, false)
+	case "watch", "w", "unwatch":
+		if addr, ok := d.intArg(args, 0); ok {
+			if cmd == "unwatch" {
+				delete(d.watches, addr)
+			} else {
+				d.watches[addr] = true
+			}
+		}
+	case "list", "l":
+		d.list()
+	case "step", "s":
+		n := int64(1)
+		if len(args) > 0 {
+			n, _ = d.intArg(args, 0)
+		}
+		for i := int64(0); i < n; i++ {
+			if stop := d.step(); stop {
+				break
+			}
+		}
+		d.printState()
+	case "continue", "c":
+		d.cont()
+		d.printState()
+	case "back", "bs":
+		n := int64(1)
+		if len(args) > 0 {
+			n, _ = d.intArg(args, 0)
+		}
+		d.back(n)
+		d.printState()
+	case "rcontinue", "rc":
+		d.reverseCont()
+		d.printState()
+	case "lastwrite":
+		if addr, ok := d.intArg(args, 0); ok {
+			d.lastWrite(addr)
+			d.printState()
+		}
+	case "before":
+		n := int64(0)
+		if len(args) > 0 {
+			n, _ = d.intArg(args, 0)
+		}
+		d.beforeOutput(n)
+		d.printState()
+	case "regs", "r":
+		d.printState()
+	case "dump", "x":
+		d.dump(args)
+	case "patch":
+		d.patch(args)
+	case "disasm", "d":
+		d.disasm(args)
+	case "input", "i":
+		for i := range args {
+			if v, ok := d.intArg(args, i); ok {
+				d.c.AddInput(v)
+			}
+		}
+	case "line":
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), cmd))
+		for _, r := range text {
+			d.c.AddInput(int64(r))
+		}
+		d.c.AddInput('\n')
+	case "output", "o":
+		for d.c.PendingOutputs() > 0 {
+			fmt.Fprintf(d.out, "output: %d\n", d.c.PopOutput())
+		}
+	default:
+		fmt.Fprintf(d.out, "unknown command %q, try help\n", cmd)
+	}
+	return false
+}
+
+func (d *Debugger) intArg(args []string, i int) (int64, bool) {
+	if i >= len(args) {
+		fmt.Fprintln(d.out, "missing argument")
+		return 0, false
+	}
+	v, err := strconv.ParseInt(args[i], 10, 64)
+	if err != nil {
+		fmt.Fprintf(d.out, "bad number %q\n", args[i])
+		return 0, false
+	}
+	return v, true
+}
+
+func (d *Debugger) setBreak(args []string, on bool) {
+	if len(args) == 0 {
+		fmt.Fprintln(d.out, "missing argument")
+		return
+	}
+	switch args[0] {
+	case "input":
+		d.toggleOp(STORE, on)
+	case "output":
+		d.toggleOp(OUTPUT, on)
+	case "op":
+		if len(args) < 2 {
+			fmt.Fprintln(d.out, "missing opcode")
+			return
+		}
+		op, ok := LookupOpCode(strings.ToUpper(args[1]))
+		if !ok {
+			fmt.Fprintf(d.out, "unknown opcode %q\n", args[1])
+			return
+		}
+		d.toggleOp(op, on)
+	default:
+		if addr, ok := d.intArg(args, 0); ok {
+			if on {
+				d.breakpoints[addr] = true
+			} else {
+				delete(d.breakpoints, addr)
+			}
+		}
+	}
+}
+
+func (d *Debugger) toggleOp(op OpCode, on bool) {
+	if on {
+		d.opBreaks[op] = true
+	} else {
+		delete(d.opBreaks, op)
+	}
+}
+
+func (d *Debugger) list() {
+	addrs := []int64{}
+	for a := range d.breakpoints {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	for _, a := range addrs {
+		fmt.Fprintf(d.out, "break %d\n", a)
+	}
+	for op := range d.opBreaks {
+		fmt.Fprintf(d.out, "break op %v\n", op)
+	}
+	addrs = addrs[:0]
+	for a := range d.watches {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	for _, a := range addrs {
+		fmt.Fprintf(d.out, "watch %d\n", a)
+	}
+}
+
+// step executes one instruction and reports whether execution should stop.
+func (d *Debugger) step() bool {
+	if d.c.finished {
+		fmt.Fprintln(d.out, "program has halted")
+		return true
+	}
+	if d.c.inputBlocked && len(d.c.inputs) == 0 {
+		fmt.Fprintln(d.out, "waiting for input")
+		return true
+	}
+	d.hits = d.hits[:0]
+	outputs := len(d.c.outputs)
+	if err := d.record(); err != nil {
+		fmt.Fprintln(d.out, err)
+		return true
+	}
+	for i := outputs; i < len(d.c.outputs); i++ {
+		fmt.Fprintf(d.out, "output: %d\n", d.c.outputs[i])
+	}
+	for _, h := range d.hits {
+		fmt.Fprintln(d.out, h)
+	}
+	if d.c.inputBlocked {
+		fmt.Fprintln(d.out, "waiting for input")
+		return true
+	}
+	return len(d.hits) > 0 || d.c.finished
+}
+
+// cont runs until a breakpoint or watchpoint fires, the program halts or it
+// needs input. The instruction at the current pos always runs, so continuing
+// from a breakpoint moves past it.
+func (d *Debugger) cont() {
+	for first := true; ; first = false {
+		if !first && d.shouldBreak() {
+			return
+		}
+		if stop := d.step(); stop {
+			return
+		}
+	}
+}
+
+func (d *Debugger) shouldBreak() bool {
+	if d.breakpoints[d.c.pos] {
+		fmt.Fprintf(d.out, "breakpoint at %d\n", d.c.pos)
+		return true
+	}
+	if len(d.opBreaks) > 0 {
+		raw, _ := d.c.memory.Read(d.c.pos)
+		if in, err := decode(raw); err == nil && d.opBreaks[in.op] {
+			fmt.Fprintf(d.out, "break on %v at %d\n", in.op, d.c.pos)
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Debugger) printState() {
+	state := "running"
+	if d.c.finished {
+		state = "halted"
+	} else if d.c.err != nil {
+		state = "failed"
+	} else if d.c.inputBlocked {
+		state = "input blocked"
+	}
+	fmt.Fprintf(d.out, "pos=%d rb=%d %s inputs=%d outputs=%d\n",
+		d.c.pos, d.c.relativeBase, state, len(d.c.inputs), len(d.c.outputs))
+	if !d.c.finished {
+		if l, ok := d.lineAt(d.c.pos); ok {
+			fmt.Fprintf(d.out, "=> %v\n", l)
+		}
+	}
+}
+
+// lineAt disassembles the instruction at addr in live memory.
+func (d *Debugger) lineAt(addr int64) (Line, bool) {
+	raw, err := d.c.memory.Read(addr)
+	if err != nil {
+		return Line{}, false
+	}
+	in, err := decode(raw)
+	if err != nil {
+		return Line{Addr: addr, Words: []int64{raw}, Data: true}, true
+	}
+	words := make([]int64, 1+in.op.Params())
+	for i := range words {
+		words[i], _ = d.c.memory.Read(addr + int64(i))
+	}
+	return Line{Addr: addr, Words: words, Op: in.op, Modes: in.modes}, true
+}
+
+func (d *Debugger) dump(args []string) {
+	addr, ok := d.intArg(args, 0)
+	if !ok {
+		return
+	}
+	n := int64(8)
+	if len(args) > 1 {
+		if n, ok = d.intArg(args, 1); !ok {
+			return
+		}
+	}
+	for i := int64(0); i < n; i += 8 {
+		words := []string{}
+		for j := i; j < n && j < i+8; j++ {
+			v, err := d.c.memory.Read(addr + j)
+			if err != nil {
+				fmt.Fprintln(d.out, err)
+				return
+			}
+			words = append(words, strconv.FormatInt(v, 10))
+		}
+		fmt.Fprintf(d.out, "%6d: %s\n", addr+i, strings.Join(words, " "))
+	}
+}
+
+func (d *Debugger) patch(args []string) {
+	addr, ok := d.intArg(args, 0)
+	if !ok {
+		return
+	}
+	for i := 1; i < len(args); i++ {
+		v, ok := d.intArg(args, i)
+		if !ok {
+			return
+		}
+		if err := d.c.Write(addr+int64(i-1), v); err != nil {
+			fmt.Fprintln(d.out, err)
+			return
+		}
+	}
+	if len(d.history) > 0 {
+		// undoing steps across a patch would mix old and new code
+		d.history = d.history[:0]
+		d.produced, d.dropped, d.forgotten = 0, 0, true
+		fmt.Fprintln(d.out, "patched, step history cleared")
+	}
+}
+
+func (d *Debugger) disasm(args []string) {
+	addr := d.c.pos
+	n := int64(8)
+	ok := true
+	if len(args) > 0 {
+		if addr, ok = d.intArg(args, 0); !ok {
+			return
+		}
+	}
+	if len(args) > 1 {
+		if n, ok = d.intArg(args, 1); !ok {
+			return
+		}
+	}
+	for i := int64(0); i < n; i++ {
+		l, ok := d.lineAt(addr)
+		if !ok {
+			return
+		}
+		marker := "  "
+		if addr == d.c.pos {
+			marker = "=>"
+		} else if d.breakpoints[addr] {
+			marker = "* "
+		}
+		fmt.Fprintf(d.out, "%s%v\n", marker, l)
+		addr += int64(len(l.Words))
+	}
+}
@@ -0,0 +1,105 @@
+package intcode
+
+// maxCachedAddress bounds the decode cache so a jump into far-away scratch
+// memory doesn't allocate a huge table.
+const maxCachedAddress = 1 << 20
+
+// instruction is a decoded opcode word. A zero op marks an empty cache slot.
+type instruction struct {
+	op    OpCode
+	modes [3]ParameterMode
+	raw   int64
+}
+
+// decode splits raw into its opcode and parameter modes arithmetically. Any
+// mode digits beyond the third must still be valid but are otherwise unused.
+func decode(raw int64) (instruction, error) {
+	in := instruction{raw: raw}
+	if raw < 0 {
+		return in, ErrUnknownOpCode
+	}
+	in.op = OpCode(raw % 100)
+	m := raw / 100
+	for i := 0; m > 0; i++ {
+		mode := ParameterMode(m % 10)
+		if mode > RELATIVE {
+			return in, ErrInvalidMode
+		}
+		if i < len(in.modes) {
+			in.modes[i] = mode
+		}
+		m /= 10
+	}
+	switch in.op {
+	case ADDITION, MULTIPLY, STORE, OUTPUT, JIT, JIF, LT, EQ, RBO, QUIT:
+		return in, nil
+	}
+	if _, ok := opTable[in.op]; ok {
+		return in, nil
+	}
+	return in, ErrUnknownOpCode
+}
+
+// fetch returns the decoded instruction at c.pos, going through the decode
+// cache. Entries are dropped by invalidate whenever their address is written.
+func (c *Computer) fetch() (instruction, error) {
+	if c.pos >= 0 && c.pos < int64(len(c.decoded)) && c.decoded[c.pos].op != 0 {
+		return c.decoded[c.pos], nil
+	}
+
+	raw, err := c.memory.Read(c.pos)
+	if err != nil {
+		return instruction{}, c.newError(ErrBadAddress, 0, c.pos)
+	}
+	if _, ok := c.bigs[c.pos]; ok {
+		return instruction{}, c.newError(ErrOverflow, 0, c.pos)
+	}
+	in, err := decode(raw)
+	if err != nil {
+		return in, c.newError(err, raw, 0)
+	}
+
+	if !c.uncached && c.pos < maxCachedAddress {
+		if c.pos >= int64(len(c.decoded)) {
+			size := c.memory.HighWater()
+			if size <= c.pos {
+				size = c.pos + 1
+			}
+			if size > maxCachedAddress {
+				size = maxCachedAddress
+			}
+			grown := make([]instruction, size)
+			copy(grown, c.decoded)
+			c.decoded = grown
+		}
+		c.decoded[c.pos] = in
+	}
+	return in, nil
+}
+
+func (c *Computer) invalidate(addr int64) {
+	if addr >= 0 && addr < int64(len(c.decoded)) {
+		c.decoded[addr] = instruction{}
+	}
+}
+
+// writeMemory is the single path for writes so the decode cache stays in
+// step with self-modifying programs.
+func (c *Computer) writeMemory(addr int64, value int64) error {
+	var old int64
+	if c.onWrite != nil {
+		old, _ = c.memory.Read(addr)
+	}
+	if err := c.memory.Write(addr, value); err != nil {
+		return err
+	}
+	c.invalidate(addr)
+	if c.onWrite != nil {
+		c.onWrite(addr, old, value)
+	}
+	// after onWrite, which may want to know what was there
+	if c.bigs != nil {
+		delete(c.bigs, addr)
+	}
+	return nil
+}
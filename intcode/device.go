@@ -0,0 +1,217 @@
+package intcode
+
+// InputDevice supplies input when the program asks for it. Input is only
+// called when a STORE finds nothing queued; returning no values leaves the
+// computer blocked the same as without a device, except that running it
+// again asks the device again rather than failing with ErrInputUnderflow.
+type InputDevice interface {
+	Input() ([]int64, error)
+}
+
+// OutputDevice takes the program's output a frame at a time, so a device
+// that talks in x, y, tile triples sees whole triples.
+type OutputDevice interface {
+	FrameSize() int
+	Output(frame []int64) error
+}
+
+// AttachInput connects d as the source of input, or detaches the current
+// one when d is nil. Inputs queued with AddInput are still used first.
+func (c *Computer) AttachInput(d InputDevice) {
+	c.input = d
+}
+
+// AttachOutput connects d as the destination of output, or detaches the
+// current one when d is nil. Complete frames go to d as soon as they are
+// produced instead of being queued for PopOutput, so RunUntilOutput only
+// stops for an incomplete frame left when the program halts or blocks.
+func (c *Computer) AttachOutput(d OutputDevice) {
+	c.output = d
+}
+
+// poll asks the input device for more input.
+func (c *Computer) poll() error {
+	values, err := c.input.Input()
+	if err != nil {
+		return err
+	}
+	c.AddInput(values...)
+	return nil
+}
+
+// deliver hands every complete frame of pending output to the output
+// device after the instruction at pos has run, blaming any failure on that
+// instruction. A frame with a value beyond int64 fails with ErrOverflow and
+// stops the computer like any device failure, but is left queued whole for
+// PopBigOutput.
+func (c *Computer) deliver(pos int64) error {
+	raw, _ := c.memory.Read(pos)
+	fail := func(err error) error {
+		return &Error{err, pos, raw, c.relativeBase, 0}
+	}
+	n := c.output.FrameSize()
+	if n < 1 {
+		return fail(ErrFrameSize)
+	}
+	for len(c.outputs) >= n {
+		for i := 0; i < n; i++ {
+			if _, ok := c.bigOutputs[i]; ok {
+				return fail(ErrOverflow)
+			}
+		}
+		frame := make([]int64, n)
+		for i := range frame {
+			frame[i] = c.outputs[0]
+			c.outputs = c.outputs[1:]
+			c.shiftBigOutputs()
+		}
+		if err := c.output.Output(frame); err != nil {
+			return fail(err)
+		}
+	}
+	return nil
+}
+
+// Point is a position on a device's grid.
+type Point struct {
+	X int64
+	Y int64
+}
+
+// Display is the arcade cabinet screen of day 13. The program draws with
+// x, y, tile triples, except that x=-1, y=0 sets the score instead.
+type Display struct {
+	Tiles map[Point]int64
+	Score int64
+	// Draw, when set, is told about every tile drawn
+	Draw func(p Point, tile int64)
+}
+
+// NewDisplay returns a blank Display.
+func NewDisplay() *Display {
+	return &Display{Tiles: make(map[Point]int64)}
+}
+
+func (d *Display) FrameSize() int {
+	return 3
+}
+
+func (d *Display) Output(frame []int64) error {
+	p := Point{frame[0], frame[1]}
+	if p == (Point{-1, 0}) {
+		d.Score = frame[2]
+		return nil
+	}
+	d.Tiles[p] = frame[2]
+	if d.Draw != nil {
+		d.Draw(p, frame[2])
+	}
+	return nil
+}
+
+// Count returns how many tiles on screen are tile.
+func (d *Display) Count(tile int64) int {
+	n := 0
+	for _, t := range d.Tiles {
+		if t == tile {
+			n++
+		}
+	}
+	return n
+}
+
+// Joystick is the arcade cabinet's joystick: each time the program reads
+// it, Tilt says which way it is pushed, -1 for left, 0 neutral, 1 right.
+type Joystick struct {
+	Tilt func() int64
+}
+
+func (j *Joystick) Input() ([]int64, error) {
+	return []int64{j.Tilt()}, nil
+}
+
+// Camera is a robot's camera, as on day 11's hull painting robot: each time
+// the program reads it, Look returns what is in view.
+type Camera struct {
+	Look func() int64
+}
+
+func (cam *Camera) Input() ([]int64, error) {
+	return []int64{cam.Look()}, nil
+}
+
+// NetworkCard is a day 23 NIC. The program reads its address first, then
+// x, y pairs of packets delivered to it, or -1 when none are waiting, and
+// sends dest, x, y triples through Send.
+type NetworkCard struct {
+	Address int64
+	Send    func(dest int64, x int64, y int64)
+	booted  bool
+	queue   []int64
+	// polls counts reads since the card last sent or received anything
+	polls int
+}
+
+// NewNetworkCard returns a card for address that sends through send.
+func NewNetworkCard(address int64, send func(dest int64, x int64, y int64)) *NetworkCard {
+	return &NetworkCard{Address: address, Send: send}
+}
+
+// Deliver queues a packet for the program to read.
+func (n *NetworkCard) Deliver(x int64, y int64) {
+	n.queue = append(n.queue, x, y)
+	n.polls = 0
+}
+
+// Idle reports whether the program has nothing queued and has polled for
+// packets more than once since it last sent or received one.
+func (n *NetworkCard) Idle() bool {
+	return len(n.queue) == 0 && n.polls > 1
+}
+
+func (n *NetworkCard) Input() ([]int64, error) {
+	if !n.booted {
+		n.booted = true
+		return []int64{n.Address}, nil
+	}
+	if len(n.queue) == 0 {
+		n.polls++
+		return []int64{-1}, nil
+	}
+	// hand over a whole packet so x and y can't be split
+	packet := n.queue[:2]
+	n.queue = n.queue[2:]
+	return packet, nil
+}
+
+func (n *NetworkCard) FrameSize() int {
+	return 3
+}
+
+func (n *NetworkCard) Output(frame []int64) error {
+	n.polls = 0
+	n.Send(frame[0], frame[1], frame[2])
+	return nil
+}
+
+// Pipe passes each output straight on as input to another computer, like
+// day 7's amplifiers. With no To the values are only kept in Last.
+type Pipe struct {
+	To *Computer
+	// Last is the most recent value through the pipe, Sent the count
+	Last int64
+	Sent int
+}
+
+func (p *Pipe) FrameSize() int {
+	return 1
+}
+
+func (p *Pipe) Output(frame []int64) error {
+	p.Last = frame[0]
+	p.Sent++
+	if p.To != nil {
+		p.To.AddInput(frame[0])
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package intcode
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// noFrames is an output device with a broken frame size.
+type noFrames struct{}
+
+func (noFrames) FrameSize() int             { return 0 }
+func (noFrames) Output(frame []int64) error { return nil }
+
+func TestDeliverBigOutput(t *testing.T) {
+	// [9] = 2^32 * 2^32, output it
+	c := NewComputer([]int64{1102, 1 << 32, 1 << 32, 9, 4, 9, 99, 0, 0, 0})
+	c.SetArithmetic(BIG)
+	pipe := &Pipe{}
+	c.AttachOutput(pipe)
+
+	err := c.Run()
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrOverflow) {
+		t.Fatalf("got %v, want ErrOverflow", err)
+	}
+	if e.Pos != 4 || e.Instruction != 4 {
+		t.Errorf("blamed pos %d instruction %d, want the OUTPUT at 4", e.Pos, e.Instruction)
+	}
+	if pipe.Sent != 0 {
+		t.Errorf("pipe got %d values", pipe.Sent)
+	}
+	want := new(big.Int).Lsh(big.NewInt(1), 64)
+	if c.PendingOutputs() != 1 {
+		t.Fatalf("%d outputs pending, want 1", c.PendingOutputs())
+	}
+	if got := c.PopBigOutput(); got.Cmp(want) != 0 {
+		t.Errorf("popped %v, want %v", got, want)
+	}
+}
+
+func TestDeliverFrameSize(t *testing.T) {
+	c := NewComputer([]int64{1101, 1, 2, 7, 104, 5, 99, 0})
+	c.AttachOutput(noFrames{})
+	err := c.Run()
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrFrameSize) {
+		t.Fatalf("got %v, want ErrFrameSize", err)
+	}
+	if e.Pos != 0 || e.Instruction != 1101 {
+		t.Errorf("blamed pos %d instruction %d, want the ADDITION at 0", e.Pos, e.Instruction)
+	}
+}
@@ -0,0 +1,244 @@
+package intcode
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Line is one row of a disassembly: either a decoded instruction or a run
+// of data words.
+type Line struct {
+	Addr  int64
+	Words []int64
+	Op    OpCode
+	Modes [3]ParameterMode
+	Data  bool
+}
+
+// FormatParam renders a parameter the way the disassembler and assembler
+// write it: [x] for position, #x for immediate and rb+x for relative.
+func FormatParam(mode ParameterMode, value int64) string {
+	switch mode {
+	case IMMEDIATE:
+		return "#" + strconv.FormatInt(value, 10)
+	case RELATIVE:
+		if value < 0 {
+			return "rb" + strconv.FormatInt(value, 10)
+		}
+		return "rb+" + strconv.FormatInt(value, 10)
+	default:
+		return "[" + strconv.FormatInt(value, 10) + "]"
+	}
+}
+
+// Text returns the assembly for the line without its address.
+func (l Line) Text() string {
+	if l.Data {
+		if isText(l.Words) {
+			runes := make([]rune, len(l.Words))
+			for i, w := range l.Words {
+				runes[i] = rune(w)
+			}
+			return ".data " + strconv.Quote(string(runes))
+		}
+		return ".data " + joinWords(l.Words, ", ")
+	}
+	params := make([]string, len(l.Words)-1)
+	for i := range params {
+		params[i] = FormatParam(l.Modes[i], l.Words[i+1])
+	}
+	if len(params) == 0 {
+		return l.Op.String()
+	}
+	text := l.Op.String() + " " + strings.Join(params, ", ")
+	if w := l.Op.Writes(); w > 0 && l.Modes[w-1] == IMMEDIATE {
+		// the assembler refuses immediate writes, so keep the words as they
+		// are and the instruction they'd fault as for the reader
+		return ".data " + joinWords(l.Words, ", ") + " ; " + text
+	}
+	return text
+}
+
+func (l Line) String() string {
+	return fmt.Sprintf("%6d  %-24s %s", l.Addr, joinWords(l.Words, ","), l.Text())
+}
+
+func joinWords(words []int64, sep string) string {
+	s := make([]string, len(words))
+	for i, w := range words {
+		s[i] = strconv.FormatInt(w, 10)
+	}
+	return strings.Join(s, sep)
+}
+
+func isPrintable(w int64) bool {
+	return (w >= 32 && w < 127) || w == '\n'
+}
+
+// a run of at least four printable words is almost certainly a string
+func isText(words []int64) bool {
+	if len(words) < 4 {
+		return false
+	}
+	for _, w := range words {
+		if !isPrintable(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReachableCode follows control flow from address 0 and returns the start
+// address of every instruction it can reach. Jumps are only followed when
+// their target is an immediate; to still find code after subroutine calls,
+// an ADDITION of two immediates where one is 0 written to rb+x (the usual
+// way a return address gets pushed) is also taken as a possible code address.
+func ReachableCode(program []int64) map[int64]bool {
+	size := int64(len(program))
+	code := make(map[int64]bool)
+	work := []int64{0}
+
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+		if addr < 0 || addr >= size || code[addr] {
+			continue
+		}
+		in, err := decode(program[addr])
+		if err != nil {
+			continue
+		}
+		next := addr + 1 + int64(in.op.Params())
+		if next > size {
+			continue
+		}
+		code[addr] = true
+		param := func(n int) int64 { return program[addr+int64(n)] }
+
+		switch in.op {
+		case QUIT:
+		case JIT, JIF:
+			taken := true
+			falls := true
+			if in.modes[0] == IMMEDIATE {
+				jumps := (param(1) != 0) == (in.op == JIT)
+				taken = jumps
+				falls = !jumps
+			}
+			if taken && in.modes[1] == IMMEDIATE {
+				work = append(work, param(2))
+			}
+			if falls {
+				work = append(work, next)
+			}
+		case ADDITION:
+			if in.modes[0] == IMMEDIATE && in.modes[1] == IMMEDIATE && in.modes[2] == RELATIVE &&
+				(param(1) == 0 || param(2) == 0) {
+				work = append(work, param(1)+param(2))
+			}
+			work = append(work, next)
+		default:
+			work = append(work, next)
+		}
+	}
+	return code
+}
+
+// Disassemble decodes the code reachable from address 0 and marks
+// everything else as data.
+func Disassemble(program []int64) []Line {
+	return DisassembleWith(program, ReachableCode(program))
+}
+
+// DisassembleWith disassembles program treating exactly the addresses in
+// code as instruction starts.
+func DisassembleWith(program []int64, code map[int64]bool) []Line {
+	size := int64(len(program))
+	lines := []Line{}
+	data := []int64{}
+	dataStart := int64(0)
+
+	flush := func() {
+		for _, words := range splitData(data) {
+			lines = append(lines, Line{Addr: dataStart, Words: words, Data: true})
+			dataStart += int64(len(words))
+		}
+		data = data[:0]
+	}
+
+	for addr := int64(0); addr < size; {
+		if code[addr] {
+			in, _ := decode(program[addr])
+			n := int64(1 + in.op.Params())
+			if addr+n <= size {
+				flush()
+				words := make([]int64, n)
+				copy(words, program[addr:addr+n])
+				lines = append(lines, Line{Addr: addr, Words: words, Op: in.op, Modes: in.modes})
+				addr += n
+				continue
+			}
+		}
+		if len(data) == 0 {
+			dataStart = addr
+		}
+		data = append(data, program[addr])
+		addr++
+	}
+	flush()
+	return lines
+}
+
+// splitData breaks a run of data into printable strings and rows of at most
+// eight plain numbers.
+func splitData(data []int64) [][]int64 {
+	chunks := [][]int64{}
+	for i := 0; i < len(data); {
+		j := i
+		for j < len(data) && isPrintable(data[j]) {
+			j++
+		}
+		if j-i >= 4 {
+			chunks = append(chunks, append([]int64{}, data[i:j]...))
+			i = j
+			continue
+		}
+		j = i
+		for j < len(data) && j-i < 8 {
+			// stop a numeric row where a string starts
+			k := j
+			for k < len(data) && isPrintable(data[k]) {
+				k++
+			}
+			if k-j >= 4 {
+				break
+			}
+			j++
+		}
+		if j == i {
+			j = i + 1
+		}
+		chunks = append(chunks, append([]int64{}, data[i:j]...))
+		i = j
+	}
+	return chunks
+}
+
+// Labels returns the sorted immediate jump targets in lines, which is handy
+// for marking the entry points of subroutines.
+func Labels(lines []Line) []int64 {
+	seen := map[int64]bool{}
+	for _, l := range lines {
+		if !l.Data && (l.Op == JIT || l.Op == JIF) && l.Modes[1] == IMMEDIATE {
+			seen[l.Words[2]] = true
+		}
+	}
+	targets := []int64{}
+	for t := range seen {
+		targets = append(targets, t)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+	return targets
+}
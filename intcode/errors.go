@@ -0,0 +1,47 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownOpCode  = errors.New("unknown op code")
+	ErrInvalidMode    = errors.New("invalid parameter mode")
+	ErrImmediateWrite = errors.New("write in immediate mode")
+	ErrBadAddress     = errors.New("address out of range")
+	ErrInputUnderflow = errors.New("input underflow")
+	ErrBudgetExceeded = errors.New("instruction budget exceeded")
+	ErrOverflow       = errors.New("integer overflow")
+	ErrFrameSize      = errors.New("output device frame size must be positive")
+	ErrSlice          = errors.New("scheduler time slice must be positive")
+)
+
+// Error describes why the computer stopped, along with the machine state at
+// the failing instruction. Use errors.Is against the Err* values to tell
+// the kinds apart.
+type Error struct {
+	Err          error
+	Pos          int64
+	Instruction  int64
+	RelativeBase int64
+	// Addr is the offending address for ErrBadAddress.
+	Addr int64
+}
+
+func (e *Error) Error() string {
+	if errors.Is(e.Err, ErrBadAddress) {
+		return fmt.Sprintf("intcode: %v: %d at pos %d (instruction %d, relative base %d)",
+			e.Err, e.Addr, e.Pos, e.Instruction, e.RelativeBase)
+	}
+	return fmt.Sprintf("intcode: %v at pos %d (instruction %d, relative base %d)",
+		e.Err, e.Pos, e.Instruction, e.RelativeBase)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (c *Computer) newError(err error, instruction int64, addr int64) *Error {
+	return &Error{err, c.pos, instruction, c.relativeBase, addr}
+}
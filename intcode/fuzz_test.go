@@ -0,0 +1,309 @@
+package intcode
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// fuzzSteps bounds every differential run, since generated programs are
+// free to loop.
+const fuzzSteps = 2000
+
+// genProgram builds a well-formed program from data: valid opcodes and
+// modes, immediate jumps that land on instruction starts and addresses that
+// stay near the program, followed by a halt and a little data. Once data
+// runs out every further choice is 0. It also returns a few inputs.
+func genProgram(data []byte) (program []int64, inputs []int64) {
+	next := func(n int) int {
+		if len(data) == 0 {
+			return 0
+		}
+		b := int(data[0])
+		data = data[1:]
+		return b % n
+	}
+
+	ops := []OpCode{ADDITION, MULTIPLY, STORE, OUTPUT, JIT, JIF, LT, EQ, RBO, QUIT}
+	type gen struct {
+		op    OpCode
+		modes [3]ParameterMode
+	}
+	code := make([]gen, 1+next(24))
+	starts := []int64{}
+	size := int64(0)
+	for i := range code {
+		g := gen{op: ops[next(len(ops))]}
+		for p := 0; p < g.op.Params(); p++ {
+			if g.op.Writes() == p+1 {
+				// mostly valid writes, with the odd immediate one to fault
+				g.modes[p] = []ParameterMode{POSITION, RELATIVE, POSITION, RELATIVE, POSITION, RELATIVE, POSITION, IMMEDIATE}[next(8)]
+			} else {
+				g.modes[p] = ParameterMode(next(3))
+			}
+		}
+		code[i] = g
+		starts = append(starts, size)
+		size += 1 + int64(g.op.Params())
+	}
+	// the halt and data that follow the code
+	size += 1 + 16
+
+	for _, g := range code {
+		raw := int64(g.op)
+		for p, scale := 0, int64(100); p < g.op.Params(); p, scale = p+1, scale*10 {
+			raw += int64(g.modes[p]) * scale
+		}
+		program = append(program, raw)
+		for p := 0; p < g.op.Params(); p++ {
+			var v int64
+			switch {
+			case (g.op == JIT || g.op == JIF) && p == 1 && g.modes[p] == IMMEDIATE:
+				v = starts[next(len(starts))]
+			case g.op == RBO && g.modes[p] == IMMEDIATE:
+				v = int64(next(17) - 8)
+			case g.modes[p] == RELATIVE:
+				v = int64(next(16) - 4)
+			case g.modes[p] == POSITION:
+				v = int64(next(int(size + 8)))
+			default:
+				v = int64(next(41) - 20)
+			}
+			program = append(program, v)
+		}
+	}
+	program = append(program, int64(QUIT))
+	for i := 0; i < 16; i++ {
+		program = append(program, int64(next(41)-20))
+	}
+	for i := next(4); i > 0; i-- {
+		inputs = append(inputs, int64(next(41)-20))
+	}
+	return program, inputs
+}
+
+// refResult is where a run ended up.
+type refResult struct {
+	mem     map[int64]int64
+	outputs []int64
+	pos     int64
+	rb      int64
+	halted  bool
+	blocked bool
+	err     error
+}
+
+// refRun is a deliberately plain interpreter written straight from the
+// puzzle text, with no caching, paging or shared helpers, to check the
+// Computer against. Its only concessions are the Computer's choices on
+// matters the puzzles leave open: which error a bad program gets, and
+// that an instruction faults before it writes anything.
+func refRun(program []int64, inputs []int64, maxSteps int) refResult {
+	r := refResult{mem: map[int64]int64{}, outputs: []int64{}}
+	for i, v := range program {
+		r.mem[int64(i)] = v
+	}
+	inputs = append([]int64{}, inputs...)
+
+	for step := 0; step < maxSteps; step++ {
+		if r.pos < 0 {
+			r.err = ErrBadAddress
+			return r
+		}
+		raw := r.mem[r.pos]
+		if raw < 0 {
+			r.err = ErrUnknownOpCode
+			return r
+		}
+		op := OpCode(raw % 100)
+		modes := []int64{}
+		for m := raw / 100; m > 0; m /= 10 {
+			if m%10 > 2 {
+				r.err = ErrInvalidMode
+				return r
+			}
+			modes = append(modes, m%10)
+		}
+		for len(modes) < 3 {
+			modes = append(modes, 0)
+		}
+
+		var fault error
+		word := func(n int64) int64 { return r.mem[r.pos+n] }
+		get := func(n int64) int64 {
+			var a int64
+			switch modes[n-1] {
+			case 1:
+				return word(n)
+			case 2:
+				a = r.rb + word(n)
+			default:
+				a = word(n)
+			}
+			if a < 0 && fault == nil {
+				fault = ErrBadAddress
+			}
+			return r.mem[a]
+		}
+		put := func(n int64, v int64) {
+			var a int64
+			switch modes[n-1] {
+			case 1:
+				if fault == nil {
+					fault = ErrImmediateWrite
+				}
+			case 2:
+				a = r.rb + word(n)
+			default:
+				a = word(n)
+			}
+			if a < 0 && fault == nil {
+				fault = ErrBadAddress
+			}
+			if fault == nil {
+				r.mem[a] = v
+			}
+		}
+		b2i := func(b bool) int64 {
+			if b {
+				return 1
+			}
+			return 0
+		}
+
+		next := r.pos
+		switch op {
+		case ADDITION:
+			a, b := get(1), get(2)
+			put(3, a+b)
+			next += 4
+		case MULTIPLY:
+			a, b := get(1), get(2)
+			put(3, a*b)
+			next += 4
+		case LT:
+			a, b := get(1), get(2)
+			put(3, b2i(a < b))
+			next += 4
+		case EQ:
+			a, b := get(1), get(2)
+			put(3, b2i(a == b))
+			next += 4
+		case STORE:
+			if len(inputs) == 0 {
+				r.blocked = true
+				return r
+			}
+			put(1, inputs[0])
+			if fault == nil {
+				inputs = inputs[1:]
+			}
+			next += 2
+		case OUTPUT:
+			v := get(1)
+			if fault == nil {
+				r.outputs = append(r.outputs, v)
+			}
+			next += 2
+		case JIT, JIF:
+			cond, target := get(1), get(2)
+			if (cond != 0) == (op == JIT) {
+				next = target
+			} else {
+				next += 3
+			}
+		case RBO:
+			v := get(1)
+			if fault == nil {
+				r.rb += v
+			}
+			next += 2
+		case QUIT:
+			r.halted = true
+			return r
+		default:
+			r.err = ErrUnknownOpCode
+			return r
+		}
+		if fault != nil {
+			r.err = fault
+			return r
+		}
+		r.pos = next
+	}
+	r.err = ErrBudgetExceeded
+	return r
+}
+
+// diffRun runs program on the Computer, with and without the decode cache,
+// and on refRun, and fails t on any difference.
+func diffRun(t *testing.T, program []int64, inputs []int64) {
+	t.Helper()
+	want := refRun(program, inputs, fuzzSteps)
+	for _, uncached := range []bool{false, true} {
+		c := NewComputer(program, inputs...)
+		c.uncached = uncached
+		err := c.RunContext(context.Background(), fuzzSteps)
+
+		if (err == nil) != (want.err == nil) || (err != nil && !errors.Is(err, want.err)) {
+			t.Fatalf("uncached=%v %v: error %v, reference %v", uncached, program, err, want.err)
+		}
+		if c.Finished() != want.halted || c.InputBlocked() != want.blocked {
+			t.Fatalf("uncached=%v %v: halted %v blocked %v, reference %v %v",
+				uncached, program, c.Finished(), c.InputBlocked(), want.halted, want.blocked)
+		}
+		if c.Pos() != want.pos || c.RelativeBase() != want.rb {
+			t.Fatalf("uncached=%v %v: pos %d rb %d, reference %d %d",
+				uncached, program, c.Pos(), c.RelativeBase(), want.pos, want.rb)
+		}
+		outputs := []int64{}
+		for c.PendingOutputs() > 0 {
+			outputs = append(outputs, c.PopOutput())
+		}
+		if len(outputs) != len(want.outputs) {
+			t.Fatalf("uncached=%v %v: outputs %v, reference %v", uncached, program, outputs, want.outputs)
+		}
+		for i := range outputs {
+			if outputs[i] != want.outputs[i] {
+				t.Fatalf("uncached=%v %v: outputs %v, reference %v", uncached, program, outputs, want.outputs)
+			}
+		}
+		for addr, v := range want.mem {
+			if got, _ := c.Read(addr); got != v {
+				t.Fatalf("uncached=%v %v: [%d] = %d, reference %d", uncached, program, addr, got, v)
+			}
+		}
+	}
+}
+
+func FuzzDifferential(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{5, 2, 0, 1, 3, 7, 11, 3, 4, 1})
+	f.Add([]byte("a longer seed to get a few more instructions going"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		program, inputs := genProgram(data)
+		diffRun(t, program, inputs)
+	})
+}
+
+// TestDifferential gives plain go test runs a broad sweep without -fuzz.
+func TestDifferential(t *testing.T) {
+	rng := rand.New(rand.NewSource(2019))
+	data := make([]byte, 128)
+	for i := 0; i < 5000; i++ {
+		rng.Read(data)
+		program, inputs := genProgram(data)
+		diffRun(t, program, inputs)
+	}
+}
+
+// TestDifferentialCountdown checks the reference against a program with a
+// known answer, so the two can't agree on something wrong.
+func TestDifferentialCountdown(t *testing.T) {
+	r := refRun(countdown(10), nil, fuzzSteps)
+	if !r.halted || len(r.outputs) != 1 || r.outputs[0] != 0 {
+		t.Fatalf("reference countdown: %+v", r)
+	}
+	diffRun(t, countdown(10), nil)
+}
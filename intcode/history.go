@@ -0,0 +1,197 @@
+package intcode
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// maxHistory bounds the undo log; the oldest steps are forgotten first.
+const maxHistory = 1 << 20
+
+// undo holds what it takes to reverse one executed instruction: the state
+// it started from and anything it consumed, produced or overwrote.
+type undo struct {
+	pos          int64
+	relativeBase int64
+	inputBlocked bool
+	finished     bool
+	highWater    int64
+	// device is set when the instruction read from an input device or
+	// handed a frame to an output device, which can't be taken back
+	device bool
+	// consumed is set when the instruction took input
+	consumed bool
+	input    int64
+	output   bool
+	wrote    bool
+	addr     int64
+	old      int64
+	oldBig   *big.Int
+}
+
+// pendingWrite is the write made by the instruction being recorded.
+type pendingWrite struct {
+	addr   int64
+	old    int64
+	oldBig *big.Int
+}
+
+// record executes one instruction like Computer.Step, adding it to the undo
+// log when it ran. A STORE that only blocked, or an instruction that
+// failed, left nothing to undo.
+func (d *Debugger) record() error {
+	c := d.c
+	u := undo{pos: c.pos, relativeBase: c.relativeBase, inputBlocked: c.inputBlocked,
+		finished: c.finished, highWater: c.memory.highWater}
+	raw, _ := c.memory.Read(c.pos)
+	in, _ := decode(raw)
+	if len(c.inputs) > 0 {
+		u.input = c.inputs[0]
+	}
+	inputs, outputs := len(c.inputs), len(c.outputs)
+	d.write = nil
+	if err := c.Step(); err != nil {
+		return err
+	}
+	if c.inputBlocked {
+		return nil
+	}
+	u.consumed = len(c.inputs) < inputs
+	u.output = len(c.outputs) > outputs
+	// an empty queue means the STORE polled the device, and an output that
+	// didn't stay queued went out in a frame
+	u.device = (in.op == STORE && c.input != nil && inputs == 0) ||
+		(in.op == OUTPUT && c.output != nil && len(c.outputs) <= outputs)
+	if d.write != nil {
+		u.wrote, u.addr, u.old, u.oldBig = true, d.write.addr, d.write.old, d.write.oldBig
+	}
+	if u.output || (in.op == OUTPUT && u.device) {
+		d.produced++
+	}
+	if len(d.history) >= maxHistory {
+		half := len(d.history) / 2
+		for _, old := range d.history[:half] {
+			if old.output {
+				d.dropped++
+			}
+		}
+		d.history = append(d.history[:0], d.history[half:]...)
+		d.forgotten = true
+	}
+	d.history = append(d.history, u)
+	return nil
+}
+
+// stepBack reverses the last recorded instruction and returns it, or false
+// when the log is empty or the instruction did device I/O.
+func (d *Debugger) stepBack() (undo, bool) {
+	if len(d.history) == 0 || d.history[len(d.history)-1].device {
+		return undo{}, false
+	}
+	c := d.c
+	u := d.history[len(d.history)-1]
+	d.history = d.history[:len(d.history)-1]
+
+	if u.wrote {
+		c.memory.Write(u.addr, u.old)
+		c.invalidate(u.addr)
+		if c.bigs != nil {
+			delete(c.bigs, u.addr)
+		}
+		if u.oldBig != nil {
+			c.bigs[u.addr] = u.oldBig
+		}
+	}
+	if u.consumed {
+		c.inputs = append([]int64{u.input}, c.inputs...)
+	}
+	if u.output {
+		// an output the user has already popped stays gone
+		if n := len(c.outputs); n > 0 {
+			c.outputs = c.outputs[:n-1]
+			delete(c.bigOutputs, n-1)
+		}
+		d.produced--
+	}
+	c.memory.highWater = u.highWater
+	c.pos = u.pos
+	c.relativeBase = u.relativeBase
+	c.inputBlocked = u.inputBlocked
+	c.finished = u.finished
+	c.err = nil
+	return u, true
+}
+
+// back steps backwards up to n instructions.
+func (d *Debugger) back(n int64) {
+	for i := int64(0); i < n; i++ {
+		if _, ok := d.stepBack(); !ok {
+			d.historyExhausted()
+			return
+		}
+	}
+}
+
+// reverseCont steps backwards until a breakpoint or the start of the log.
+func (d *Debugger) reverseCont() {
+	for {
+		if _, ok := d.stepBack(); !ok {
+			d.historyExhausted()
+			return
+		}
+		if d.shouldBreak() {
+			return
+		}
+	}
+}
+
+// lastWrite steps backwards to just before the most recent instruction that
+// wrote addr.
+func (d *Debugger) lastWrite(addr int64) {
+	for {
+		value, _ := d.c.memory.Read(addr)
+		u, ok := d.stepBack()
+		if !ok {
+			d.historyExhausted()
+			return
+		}
+		if u.wrote && u.addr == addr {
+			fmt.Fprintf(d.out, "[%d] was written at %d: %d -> %d\n", addr, u.pos, u.old, value)
+			return
+		}
+	}
+}
+
+// beforeOutput steps backwards to just before output n, counting from 1 at
+// the first output the log has seen, or before the latest output when n is
+// 0. Outputs from steps trimmed off the log can't be reached.
+func (d *Debugger) beforeOutput(n int64) {
+	if n == 0 {
+		n = d.produced
+	}
+	if n < 1 || n > d.produced {
+		fmt.Fprintf(d.out, "only %d outputs so far\n", d.produced)
+		return
+	}
+	if n <= d.dropped {
+		fmt.Fprintf(d.out, "output %d is older than the oldest remembered step\n", n)
+		return
+	}
+	for d.produced >= n {
+		if _, ok := d.stepBack(); !ok {
+			d.historyExhausted()
+			return
+		}
+	}
+}
+
+func (d *Debugger) historyExhausted() {
+	if n := len(d.history); n > 0 {
+		// only device I/O stops stepBack with history left
+		fmt.Fprintf(d.out, "can't step back over device I/O at %d\n", d.history[n-1].pos)
+	} else if d.forgotten {
+		fmt.Fprintln(d.out, "reached the oldest remembered step")
+	} else {
+		fmt.Fprintln(d.out, "reached the start of the recording")
+	}
+}
@@ -0,0 +1,85 @@
+package intcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// feed is an input device handing out its values one poll at a time.
+type feed []int64
+
+func (f *feed) Input() ([]int64, error) {
+	if len(*f) == 0 {
+		return nil, nil
+	}
+	v := (*f)[0]
+	*f = (*f)[1:]
+	return []int64{v}, nil
+}
+
+func TestStepBackHighWater(t *testing.T) {
+	// [100] = 1 + 2
+	c := NewComputer([]int64{1101, 1, 2, 100, 99})
+	d := NewDebugger(c, &bytes.Buffer{})
+	d.Exec("step")
+	if got := c.MemoryHighWater(); got != 101 {
+		t.Fatalf("high water %d after the write, want 101", got)
+	}
+	d.Exec("back")
+	if got := c.MemoryHighWater(); got != 5 {
+		t.Errorf("high water %d after stepping back, want 5", got)
+	}
+	if v, _ := c.Read(100); v != 0 {
+		t.Errorf("[100] = %d after stepping back", v)
+	}
+}
+
+func TestStepBackDeviceIO(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		program []int64
+		attach  func(c *Computer)
+		steps   int
+		// back is how many steps can be undone before the device I/O
+		back int64
+		at   string
+	}{
+		{"queued input", []int64{3, 9, 1001, 9, 1, 9, 99, 0, 0, 0}, func(c *Computer) { c.AddInput(5) }, 2, 2, ""},
+		{"input device", []int64{3, 9, 1001, 9, 1, 9, 99, 0, 0, 0}, func(c *Computer) { c.AttachInput(&feed{5}) }, 2, 1, "at 0"},
+		{"part of a frame", []int64{104, 7, 104, 8, 99}, func(c *Computer) { c.AttachOutput(NewDisplay()) }, 2, 2, ""},
+		{"output device", []int64{1101, 1, 2, 9, 104, 7, 1101, 3, 4, 9, 99},
+			func(c *Computer) { c.AttachOutput(&Pipe{}) }, 3, 1, "at 4"},
+	} {
+		c := NewComputer(tc.program)
+		tc.attach(c)
+		var out bytes.Buffer
+		d := NewDebugger(c, &out)
+		for i := 0; i < tc.steps; i++ {
+			d.Exec("step")
+		}
+		var back int64
+		for {
+			if _, ok := d.stepBack(); !ok {
+				break
+			}
+			back++
+		}
+		if back != tc.back {
+			t.Errorf("%s: stepped back %d, want %d", tc.name, back, tc.back)
+		}
+		if tc.name == "queued input" && c.PendingInputs() != 1 {
+			t.Errorf("%s: %d inputs queued after stepping back, want 1", tc.name, c.PendingInputs())
+		}
+		out.Reset()
+		d.Exec("back")
+		msg := out.String()
+		if tc.at == "" {
+			if !strings.Contains(msg, "start of the recording") {
+				t.Errorf("%s: %q", tc.name, msg)
+			}
+		} else if !strings.Contains(msg, "can't step back over device I/O "+tc.at) {
+			t.Errorf("%s: %q, want device I/O %s", tc.name, msg, tc.at)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+// Package intcode implements the Intcode computer used by the intcode days.
+package intcode
+
+import (
+	"context"
+	"errors"
+	"math/big"
+)
+
+type ParameterMode int
+
+const (
+	POSITION ParameterMode = iota
+	IMMEDIATE
+	RELATIVE
+)
+
+type OpCode int
+
+const (
+	ADDITION OpCode = iota + 1
+	MULTIPLY        // 2
+	STORE           // 3
+	OUTPUT          // 4
+	JIT             // 5
+	JIF             // 6
+	LT              // 7
+	EQ              // 8
+	RBO             // 9
+	QUIT     OpCode = 99
+)
+
+// ctxCheckInterval is how many instructions run between checks for
+// cancellation, so a program stuck in a loop without I/O can still be
+// stopped.
+const ctxCheckInterval = 1024
+
+// Computer is a single Intcode machine with queued inputs and outputs.
+type Computer struct {
+	memory       *Memory
+	inputs       []int64
+	relativeBase int64
+	pos          int64
+	finished     bool
+	outputs      []int64
+	inputBlocked bool
+	err          error
+	// decoded caches instructions by address; uncached turns it off
+	decoded  []instruction
+	uncached bool
+	// onWrite, when set, sees every write along with the value it replaced
+	onWrite  func(addr int64, old int64, value int64)
+	tracer   *Tracer
+	profiler *Profiler
+	coverage *Coverage
+	recorder *Recorder
+	arith    Arithmetic
+	// attached devices, see AttachInput and AttachOutput
+	input  InputDevice
+	output OutputDevice
+	// values beyond int64 in BIG mode, by address and by output queue index
+	bigs       map[int64]*big.Int
+	bigOutputs map[int]*big.Int
+}
+
+// NewComputer returns a Computer running a copy of program, with any
+// initial inputs already queued.
+func NewComputer(program []int64, inputs ...int64) *Computer {
+	c := &Computer{memory: NewMemory(program), inputs: []int64{}, outputs: []int64{}}
+	c.AddInput(inputs...)
+	return c
+}
+
+// Finished reports whether the program has executed QUIT.
+func (c *Computer) Finished() bool {
+	return c.finished
+}
+
+// Err returns the error that stopped the computer, if any.
+func (c *Computer) Err() error {
+	return c.err
+}
+
+// InputBlocked reports whether the last STORE found no input waiting.
+func (c *Computer) InputBlocked() bool {
+	return c.inputBlocked
+}
+
+// Pos returns the current instruction pointer.
+func (c *Computer) Pos() int64 {
+	return c.pos
+}
+
+// RelativeBase returns the current relative base.
+func (c *Computer) RelativeBase() int64 {
+	return c.relativeBase
+}
+
+// Read returns the value held at addr. Values beyond int64 need ReadBig.
+func (c *Computer) Read(addr int64) (int64, error) {
+	if _, ok := c.bigs[addr]; ok {
+		return 0, bigValue(addr)
+	}
+	return c.memory.Read(addr)
+}
+
+// Write stores value at addr, e.g. to patch the program before running it.
+func (c *Computer) Write(addr int64, value int64) error {
+	return c.writeMemory(addr, value)
+}
+
+// MemoryHighWater returns one past the highest address written so far.
+func (c *Computer) MemoryHighWater() int64 {
+	return c.memory.HighWater()
+}
+
+// AddInput queues values for STORE to consume and unblocks the computer.
+func (c *Computer) AddInput(inputs ...int64) {
+	c.inputs = append(c.inputs, inputs...)
+	if len(inputs) > 0 {
+		c.inputBlocked = false
+	}
+}
+
+// PendingInputs returns the number of queued inputs not yet consumed.
+func (c *Computer) PendingInputs() int {
+	return len(c.inputs)
+}
+
+// PendingOutputs returns the number of outputs not yet popped.
+func (c *Computer) PendingOutputs() int {
+	return len(c.outputs)
+}
+
+// PopOutput removes and returns the oldest pending output.
+func (c *Computer) PopOutput() int64 {
+	if len(c.outputs) > 0 {
+		if _, ok := c.bigOutputs[0]; ok {
+			panic("Output needs more than 64 bits, use PopBigOutput")
+		}
+		r := c.outputs[0]
+		c.outputs = c.outputs[1:]
+		c.shiftBigOutputs()
+		return r
+	} else {
+		panic("Computer has no outputs to provide")
+	}
+}
+
+// Step executes a single instruction. A STORE with no input waiting leaves
+// the instruction pointer in place and flags the computer as input blocked;
+// stepping it again before adding input returns ErrInputUnderflow, which
+// AddInput recovers from. Any other error means the computer is stuck and
+// keeps returning it.
+func (c *Computer) Step() error {
+	if c.err != nil {
+		return c.err
+	}
+	pos := c.pos
+	if err := cycle(c); err != nil {
+		// running dry is the caller's mistake, not a fault in the program
+		if !errors.Is(err, ErrInputUnderflow) {
+			c.err = err
+		}
+		return err
+	}
+	if c.output != nil {
+		if err := c.deliver(pos); err != nil {
+			c.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Run executes until the program halts, blocks waiting for input or fails.
+// Running a computer that is still blocked with no new input returns
+// ErrInputUnderflow rather than spinning, and leaves it ready to carry on
+// once input is added.
+func (c *Computer) Run() error {
+	return c.run(context.Background(), 0, -1)
+}
+
+// RunUntilOutput executes until at least n outputs are pending, the program
+// halts, it blocks waiting for input or fails.
+func (c *Computer) RunUntilOutput(n int) error {
+	return c.run(context.Background(), 0, n)
+}
+
+// RunContext is Run with limits. It gives up with ErrBudgetExceeded once
+// maxSteps instructions have executed, or 0 for no limit, and with ctx's
+// error once ctx is done. Either way the returned *Error holds the state
+// at that point and the computer can carry on from there.
+func (c *Computer) RunContext(ctx context.Context, maxSteps int64) error {
+	return c.run(ctx, maxSteps, -1)
+}
+
+// RunUntilOutputContext is RunUntilOutput with the limits of RunContext.
+func (c *Computer) RunUntilOutputContext(ctx context.Context, n int, maxSteps int64) error {
+	return c.run(ctx, maxSteps, n)
+}
+
+// run steps until the program halts or blocks, or until that many outputs
+// are pending unless outputs is negative.
+func (c *Computer) run(ctx context.Context, maxSteps int64, outputs int) error {
+	for steps := int64(0); !c.finished && (outputs < 0 || len(c.outputs) < outputs); steps++ {
+		if maxSteps > 0 && steps >= maxSteps {
+			return c.stopped(ErrBudgetExceeded)
+		}
+		if steps%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return c.stopped(err)
+			}
+		}
+		if err := c.Step(); err != nil {
+			return err
+		}
+		if c.inputBlocked {
+			break
+		}
+	}
+	return nil
+}
+
+// stopped describes a run cut short by a limit rather than a fault, so it
+// is returned without being kept in c.err.
+func (c *Computer) stopped(err error) error {
+	raw, _ := c.memory.Read(c.pos)
+	return c.newError(err, raw, 0)
+}
+
+// operands resolves the parameters of the instruction at c.pos. The first
+// failure is kept in err and every later call becomes a no-op, so an
+// instruction can be written out in full and checked once at the end.
+type operands struct {
+	c   *Computer
+	in  instruction
+	err error
+	// the write made by the instruction, for the tracer
+	wrote bool
+	waddr int64
+	wval  int64
+}
+
+func (o *operands) fail(err error, addr int64) {
+	if o.err == nil {
+		o.err = o.c.newError(err, o.in.raw, addr)
+	}
+}
+
+func (o *operands) load(addr int64) int64 {
+	if o.err != nil {
+		return 0
+	}
+	if _, ok := o.c.bigs[addr]; ok {
+		o.fail(ErrOverflow, addr)
+		return 0
+	}
+	v, err := o.c.memory.Read(addr)
+	if err != nil {
+		o.fail(ErrBadAddress, addr)
+	}
+	return v
+}
+
+// read returns the value of parameter n (1-based)
+func (o *operands) read(n int) int64 {
+	value := o.load(o.c.pos + int64(n))
+	switch o.in.modes[n-1] {
+	case POSITION:
+		return o.load(value)
+	case IMMEDIATE:
+		return value
+	default:
+		return o.load(o.c.relativeBase + value)
+	}
+}
+
+// addr returns the address that parameter n (1-based) writes to
+func (o *operands) addr(n int) int64 {
+	value := o.load(o.c.pos + int64(n))
+	switch o.in.modes[n-1] {
+	case POSITION:
+		return value
+	case IMMEDIATE:
+		o.fail(ErrImmediateWrite, 0)
+		return 0
+	default:
+		return o.c.relativeBase + value
+	}
+}
+
+func (o *operands) write(addr int64, value int64) {
+	if o.err != nil {
+		return
+	}
+	if err := o.c.writeMemory(addr, value); err != nil {
+		o.fail(ErrBadAddress, addr)
+		return
+	}
+	o.wrote, o.waddr, o.wval = true, addr, value
+}
+
+func cycle(c *Computer) error {
+
+	in, err := c.fetch()
+	if err != nil {
+		return err
+	}
+	o := operands{c: c, in: in}
+	next := c.pos
+	if c.tracer != nil {
+		c.tracer.begin(c, in)
+		defer c.tracer.end(c, in, &o)
+	}
+	if c.profiler != nil {
+		c.profiler.begin(c, in)
+		defer c.profiler.end(c, in, &o, c.pos)
+	}
+
+	switch in.op {
+
+	case ADDITION, MULTIPLY, LT, EQ:
+		if c.arith == BIG {
+			o.bigArith(in.op)
+		} else {
+			o.arith(in.op)
+		}
+		next = c.pos + 4
+
+	case STORE:
+		if len(c.inputs) == 0 && c.input != nil {
+			if err := c.poll(); err != nil {
+				o.fail(err, 0)
+				return o.err
+			}
+		}
+		if len(c.inputs) == 0 {
+			// a device gets asked again each time, so it can't underflow
+			if c.inputBlocked && c.input == nil {
+				return c.newError(ErrInputUnderflow, in.raw, 0)
+			}
+			c.inputBlocked = true
+			return nil
+		}
+		o.write(o.addr(1), c.inputs[0])
+		if o.err != nil {
+			return o.err
+		}
+		c.inputBlocked = false
+		c.inputs = c.inputs[1:]
+		next = c.pos + 2
+
+	case OUTPUT:
+		if c.arith == BIG {
+			o.outputBig()
+		} else {
+			value := o.read(1)
+			if o.err != nil {
+				return o.err
+			}
+			c.outputs = append(c.outputs, value)
+		}
+		next = c.pos + 2
+
+	case JIT:
+		value1 := o.nonZero(1)
+		value2 := o.read(2)
+		if value1 {
+			next = value2
+		} else {
+			next = c.pos + 3
+		}
+
+	case JIF:
+		value1 := o.nonZero(1)
+		value2 := o.read(2)
+		if !value1 {
+			next = value2
+		} else {
+			next = c.pos + 3
+		}
+
+	case RBO:
+		adjustment := o.read(1)
+		if o.err != nil {
+			return o.err
+		}
+		c.relativeBase += adjustment
+		next = c.pos + 2
+
+	case QUIT:
+		c.finished = true
+
+	default:
+		exec, ok := extensions[in.op]
+		if !ok {
+			o.fail(ErrUnknownOpCode, 0)
+			return o.err
+		}
+		info := opTable[in.op]
+		args := make([]int64, 0, info.params)
+		for n := 1; n <= info.params; n++ {
+			if n != info.write {
+				args = append(args, o.read(n))
+			}
+		}
+		if o.err != nil {
+			return o.err
+		}
+		result, err := exec(c, args)
+		if err != nil {
+			o.fail(err, 0)
+			return o.err
+		}
+		if info.write > 0 {
+			o.write(o.addr(info.write), result)
+		}
+		next = c.pos + 1 + int64(info.params)
+	}
+
+	if o.err != nil {
+		return o.err
+	}
+	if c.coverage != nil {
+		c.coverage.hits[c.pos]++
+	}
+	if c.recorder != nil {
+		c.recorder.step(c, in.op, &o)
+	}
+	c.pos = next
+	return nil
+}
@@ -0,0 +1,114 @@
+package intcode
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseError reports a value in program text that isn't a number.
+type ParseError struct {
+	// Index is the position the value would have had in the program.
+	Index int
+	Line  int
+	Token string
+}
+
+func (e *ParseError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("line %d: missing value at index %d", e.Line, e.Index)
+	}
+	return fmt.Sprintf("line %d: bad value %q at index %d", e.Line, e.Token, e.Index)
+}
+
+// ParseProgram parses comma separated program text. Whitespace and line
+// breaks between values are ignored, a trailing comma at the end of a line
+// is allowed and ';' starts a comment running to the end of the line, so a
+// hand written program can be laid out as
+//
+//	1,0,0,3,   ; 0: [3] = [0] + [0]
+//	99         ; 4: halt
+func ParseProgram(src string) ([]int64, error) {
+	program := []int64{}
+	for n, line := range strings.Split(src, "\n") {
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		tokens := strings.Split(line, ",")
+		for i, token := range tokens {
+			token = strings.TrimSpace(token)
+			if token == "" && i == len(tokens)-1 && i > 0 {
+				break
+			}
+			v, err := strconv.ParseInt(token, 10, 64)
+			if err != nil {
+				return nil, &ParseError{len(program), n + 1, token}
+			}
+			program = append(program, v)
+		}
+	}
+	return program, nil
+}
+
+// ReadProgram loads and parses the program in the file at path, or stdin
+// when path is "-".
+func ReadProgram(path string) ([]int64, error) {
+	var src []byte
+	var err error
+	name := path
+	if path == "-" {
+		name = "stdin"
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	program, err := ParseProgram(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return program, nil
+}
+
+// ParseInputs parses the comma separated input values given to the tools'
+// -input flag. An empty list is no inputs.
+func ParseInputs(s string) ([]int64, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	values := []int64{}
+	for _, v := range strings.Split(s, ",") {
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad input %q", v)
+		}
+		values = append(values, i)
+	}
+	return values, nil
+}
+
+// ProgramFlag registers the -program flag the days and tools share, which
+// defaults to input.txt and reads stdin when set to "-". Pass its value to
+// LoadProgram once the command line is parsed.
+func ProgramFlag() *string {
+	return flag.String("program", "input.txt", "intcode program file, - for stdin")
+}
+
+// LoadProgram is the loader for the days and tools. It reads the program at
+// path like ReadProgram, and exits with a message if it can't be loaded.
+func LoadProgram(path string) []int64 {
+	program, err := ReadProgram(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return program
+}
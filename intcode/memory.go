@@ -0,0 +1,151 @@
+package intcode
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	pageBits = 10
+	pageSize = 1 << pageBits
+	pageMask = pageSize - 1
+	// pages below this index live in a directory slice, anything further
+	// away (scratch memory at huge addresses) goes in a map
+	maxDirectoryPages = 1 << 16
+)
+
+// Memory is a sparse, paged address space that grows on demand. Reading an
+// address that has never been written returns 0 without allocating.
+type Memory struct {
+	directory [][]int64
+	far       map[int64][]int64
+	highWater int64
+}
+
+// NewMemory returns a Memory loaded with a copy of program at address 0.
+func NewMemory(program []int64) *Memory {
+	m := &Memory{}
+	for i := 0; i < len(program); i += pageSize {
+		end := i + pageSize
+		if end > len(program) {
+			end = len(program)
+		}
+		page := m.page(int64(i>>pageBits), true)
+		copy(page, program[i:end])
+	}
+	m.highWater = int64(len(program))
+	return m
+}
+
+// AddressError is returned for accesses to a negative address.
+type AddressError struct {
+	Addr int64
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("invalid memory address: %d", e.Addr)
+}
+
+func (e *AddressError) Unwrap() error {
+	return ErrBadAddress
+}
+
+func (m *Memory) page(n int64, create bool) []int64 {
+	if n < maxDirectoryPages {
+		if n < int64(len(m.directory)) && m.directory[n] != nil {
+			return m.directory[n]
+		}
+		if !create {
+			return nil
+		}
+		for int64(len(m.directory)) <= n {
+			m.directory = append(m.directory, nil)
+		}
+		m.directory[n] = make([]int64, pageSize)
+		return m.directory[n]
+	}
+
+	if p, ok := m.far[n]; ok {
+		return p
+	}
+	if !create {
+		return nil
+	}
+	if m.far == nil {
+		m.far = make(map[int64][]int64)
+	}
+	m.far[n] = make([]int64, pageSize)
+	return m.far[n]
+}
+
+// Read returns the value at addr.
+func (m *Memory) Read(addr int64) (int64, error) {
+	if addr < 0 {
+		return 0, &AddressError{addr}
+	}
+	p := m.page(addr>>pageBits, false)
+	if p == nil {
+		return 0, nil
+	}
+	return p[addr&pageMask], nil
+}
+
+// Write stores value at addr, allocating the page holding it if needed.
+func (m *Memory) Write(addr int64, value int64) error {
+	if addr < 0 {
+		return &AddressError{addr}
+	}
+	p := m.page(addr>>pageBits, true)
+	p[addr&pageMask] = value
+	if addr >= m.highWater {
+		m.highWater = addr + 1
+	}
+	return nil
+}
+
+// HighWater returns one past the highest address that has been written,
+// counting the initially loaded program.
+func (m *Memory) HighWater() int64 {
+	return m.highWater
+}
+
+// Clone returns an independent copy of m.
+func (m *Memory) Clone() *Memory {
+	n := &Memory{highWater: m.highWater}
+	n.directory = make([][]int64, len(m.directory))
+	for i, p := range m.directory {
+		if p != nil {
+			n.directory[i] = append([]int64(nil), p...)
+		}
+	}
+	if m.far != nil {
+		n.far = make(map[int64][]int64, len(m.far))
+		for k, p := range m.far {
+			n.far[k] = append([]int64(nil), p...)
+		}
+	}
+	return n
+}
+
+// eachPage calls fn with the index and contents of every allocated page in
+// ascending order.
+func (m *Memory) eachPage(fn func(n int64, page []int64) error) error {
+	for i, p := range m.directory {
+		if p != nil {
+			if err := fn(int64(i), p); err != nil {
+				return err
+			}
+		}
+	}
+	far := make([]int64, 0, len(m.far))
+	for k := range m.far {
+		far = append(far, k)
+	}
+	sort.Slice(far, func(i, j int) bool { return far[i] < far[j] })
+	for _, k := range far {
+		if err := fn(k, m.far[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package intcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// opInfo describes the shape of an instruction: its mnemonic, how many
+// parameters follow the opcode word and which of those (if any) is written.
+type opInfo struct {
+	name   string
+	params int
+	// write is the 1-based parameter that names a destination, 0 for none
+	write int
+}
+
+var opTable = map[OpCode]opInfo{
+	ADDITION: {"ADDITION", 3, 3},
+	MULTIPLY: {"MULTIPLY", 3, 3},
+	STORE:    {"STORE", 1, 1},
+	OUTPUT:   {"OUTPUT", 1, 0},
+	JIT:      {"JIT", 2, 0},
+	JIF:      {"JIF", 2, 0},
+	LT:       {"LT", 3, 3},
+	EQ:       {"EQ", 3, 3},
+	RBO:      {"RBO", 1, 0},
+	QUIT:     {"QUIT", 0, 0},
+}
+
+func (op OpCode) String() string {
+	if info, ok := opTable[op]; ok {
+		return info.name
+	}
+	return "UNKNOWN"
+}
+
+// Params returns the number of parameters op takes, or -1 if op is unknown.
+func (op OpCode) Params() int {
+	if info, ok := opTable[op]; ok {
+		return info.params
+	}
+	return -1
+}
+
+// Writes returns the 1-based parameter op writes to, or 0 if it writes none.
+func (op OpCode) Writes() int {
+	return opTable[op].write
+}
+
+// LookupOpCode returns the opcode whose mnemonic is name.
+func LookupOpCode(name string) (OpCode, bool) {
+	for op, info := range opTable {
+		if info.name == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
+
+// extensions holds the Exec of every opcode added by RegisterOpCode.
+var extensions = map[OpCode]func(c *Computer, args []int64) (int64, error){}
+
+var ErrOpCodeConflict = errors.New("opcode already registered")
+
+// OpDef describes an extension instruction for RegisterOpCode.
+type OpDef struct {
+	// Name is the mnemonic used by the assembler and disassembler.
+	Name string
+	// Params is how many parameters follow the opcode word, at most 3.
+	Params int
+	// Write is the 1-based parameter the result is stored to, 0 for none.
+	// Every other parameter is read.
+	Write int
+	// Exec is given the values of the read parameters in order and returns
+	// the value to store. An error stops the computer with that error.
+	Exec func(c *Computer, args []int64) (int64, error)
+}
+
+// RegisterOpCode adds op to the instruction set, e.g. an integer divide:
+//
+//	intcode.RegisterOpCode(10, intcode.OpDef{Name: "DIV", Params: 3, Write: 3,
+//		Exec: func(c *intcode.Computer, args []int64) (int64, error) {
+//			if args[1] == 0 {
+//				return 0, errors.New("division by zero")
+//			}
+//			return args[0] / args[1], nil
+//		}})
+//
+// Opcodes and mnemonics already in use, including the built-in ones, are
+// rejected with ErrOpCodeConflict. Registration isn't synchronised, so do
+// it before starting any computers.
+func RegisterOpCode(op OpCode, def OpDef) error {
+	switch {
+	case op <= 0 || op > 99:
+		return fmt.Errorf("opcode %d out of range 1-99", op)
+	case !isIdent(def.Name) || def.Name != strings.ToUpper(def.Name):
+		return fmt.Errorf("bad mnemonic %q, want an upper case name", def.Name)
+	case def.Params < 0 || def.Params > 3:
+		return fmt.Errorf("%s: %d parameters, want 0 to 3", def.Name, def.Params)
+	case def.Write < 0 || def.Write > def.Params:
+		return fmt.Errorf("%s: write parameter %d out of range", def.Name, def.Write)
+	case def.Exec == nil:
+		return fmt.Errorf("%s: missing Exec", def.Name)
+	}
+	if info, ok := opTable[op]; ok {
+		return fmt.Errorf("%w: %d is %s", ErrOpCodeConflict, op, info.name)
+	}
+	if other, ok := LookupOpCode(def.Name); ok {
+		return fmt.Errorf("%w: %s is %d", ErrOpCodeConflict, def.Name, other)
+	}
+	opTable[op] = opInfo{def.Name, def.Params, def.Write}
+	extensions[op] = def.Exec
+	return nil
+}
@@ -0,0 +1,136 @@
+package intcode
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// protoBuf builds the protocol buffer wire format, just enough of it for
+// pprof's profile.proto.
+type protoBuf []byte
+
+func (b *protoBuf) varint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *protoBuf) int(field int, v int64) {
+	b.varint(uint64(field) << 3)
+	b.varint(uint64(v))
+}
+
+func (b *protoBuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *protoBuf) packed(field int, vs []int64) {
+	var inner protoBuf
+	for _, v := range vs {
+		inner.varint(uint64(v))
+	}
+	b.bytes(field, inner)
+}
+
+// WritePprof writes the profile as a gzipped pprof protocol buffer with one
+// sample per address and call chain, valued in instructions. Each
+// subroutine becomes a function named after its entry address and each
+// address a line in it, so `go tool pprof -list` and the call graph views
+// work on intcode.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	strs := []string{""}
+	strIndex := map[string]int64{"": 0}
+	str := func(s string) int64 {
+		if i, ok := strIndex[s]; ok {
+			return i
+		}
+		strIndex[s] = int64(len(strs))
+		strs = append(strs, s)
+		return int64(len(strs) - 1)
+	}
+
+	var out protoBuf
+	var vt protoBuf
+	vt.int(1, str("instructions"))
+	vt.int(2, str("count"))
+	out.bytes(1, vt)
+
+	// functions are subroutine entries, locations an address inside one
+	functions := map[int64]int64{}
+	function := func(entry int64) int64 {
+		if id, ok := functions[entry]; ok {
+			return id
+		}
+		id := int64(len(functions) + 1)
+		functions[entry] = id
+		name := fmt.Sprintf("sub_%d", entry)
+		if entry == 0 {
+			name = "main"
+		}
+		var f protoBuf
+		f.int(1, id)
+		f.int(2, str(name))
+		f.int(4, str("intcode"))
+		f.int(5, entry)
+		out.bytes(5, f)
+		return id
+	}
+	type locKey struct{ entry, addr int64 }
+	locations := map[locKey]int64{}
+	location := func(entry int64, addr int64) int64 {
+		k := locKey{entry, addr}
+		if id, ok := locations[k]; ok {
+			return id
+		}
+		id := int64(len(locations) + 1)
+		locations[k] = id
+		var line protoBuf
+		line.int(1, function(entry))
+		line.int(2, addr)
+		var l protoBuf
+		l.int(1, id)
+		l.int(3, addr)
+		l.bytes(4, line)
+		out.bytes(4, l)
+		return id
+	}
+
+	keys := make([]sampleKey, 0, len(p.samples))
+	for k := range p.samples {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].stack != keys[j].stack {
+			return keys[i].stack < keys[j].stack
+		}
+		return keys[i].pc < keys[j].pc
+	})
+	for _, k := range keys {
+		// leaf first, then each caller at the return address it pushed,
+		// which sits just after the call
+		chain := p.entries(k.stack)
+		stack := []int64{location(chain[0], k.pc)}
+		for i, id := 0, k.stack; i+1 < len(chain); i, id = i+1, p.parents[id].parent {
+			stack = append(stack, location(chain[i+1], p.parents[id].ret))
+		}
+		var s protoBuf
+		s.packed(1, stack)
+		s.packed(2, []int64{p.samples[k]})
+		out.bytes(2, s)
+	}
+	for _, s := range strs {
+		out.bytes(6, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(out); err != nil {
+		return err
+	}
+	return gz.Close()
+}
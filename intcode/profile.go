@@ -0,0 +1,329 @@
+package intcode
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Profiler counts what a Computer does: executions per address and per
+// opcode, instructions spent per input consumed and per output produced,
+// and reads and writes per memory address. It also keeps a shadow call
+// stack, spotting calls by the return address pushed just before a jump
+// and returns by arrival at that address, so time can be charged to
+// subroutines.
+type Profiler struct {
+	steps  int64
+	addrs  map[int64]*addrStat
+	ops    map[OpCode]int64
+	reads  map[int64]int64
+	writes map[int64]int64
+
+	// instructions executed before each input consumed and output produced
+	inputs  []int64
+	outputs []int64
+	lastIn  int64
+	lastOut int64
+
+	// frames is the shadow call stack, stack the id of the current chain
+	frames  []frame
+	stack   int
+	parents []stackKey
+	ids     map[stackKey]int
+	calls   map[int64]int64
+	// ret is a return address pushed by the last instruction, or -1
+	ret     int64
+	samples map[sampleKey]int64
+
+	// addresses read by the pending instruction
+	pendingReads [3]int64
+	nreads       int
+}
+
+type addrStat struct {
+	count int64
+	line  Line
+}
+
+type frame struct {
+	entry int64
+	ret   int64
+	// stack is the id of the chain below this frame
+	stack int
+}
+
+// stackKey identifies a chain of calls by its innermost call, entry and
+// return address, and the id of the chain it was made from.
+type stackKey struct {
+	parent int
+	entry  int64
+	ret    int64
+}
+
+type sampleKey struct {
+	stack int
+	pc    int64
+}
+
+// NewProfiler returns an empty Profiler. Attach it with
+// Computer.SetProfiler.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		addrs:   make(map[int64]*addrStat),
+		ops:     make(map[OpCode]int64),
+		reads:   make(map[int64]int64),
+		writes:  make(map[int64]int64),
+		parents: []stackKey{{-1, 0, -1}},
+		ids:     map[stackKey]int{{-1, 0, -1}: 0},
+		calls:   make(map[int64]int64),
+		ret:     -1,
+		samples: make(map[sampleKey]int64),
+	}
+}
+
+// SetProfiler attaches p to c, or detaches profiling when p is nil.
+func (c *Computer) SetProfiler(p *Profiler) {
+	c.profiler = p
+}
+
+// Steps returns the number of instructions executed while attached.
+func (p *Profiler) Steps() int64 {
+	return p.steps
+}
+
+// begin notes the addresses the instruction reads before it can change
+// them or the relative base.
+func (p *Profiler) begin(c *Computer, in instruction) {
+	p.nreads = 0
+	for i := 0; i < in.op.Params(); i++ {
+		if in.op.Writes() == i+1 || in.modes[i] == IMMEDIATE {
+			continue
+		}
+		value, _ := c.memory.Read(c.pos + int64(i+1))
+		if in.modes[i] == RELATIVE {
+			value += c.relativeBase
+		}
+		p.pendingReads[p.nreads] = value
+		p.nreads++
+	}
+}
+
+func (p *Profiler) end(c *Computer, in instruction, o *operands, pc int64) {
+	if o.err != nil || (in.op == STORE && !o.wrote) {
+		return
+	}
+	p.steps++
+	stat := p.addrs[pc]
+	if stat == nil {
+		stat = &addrStat{line: lineAt(c, pc, in)}
+		p.addrs[pc] = stat
+	}
+	stat.count++
+	p.ops[in.op]++
+	p.samples[sampleKey{p.stack, pc}]++
+	for _, addr := range p.pendingReads[:p.nreads] {
+		p.reads[addr]++
+	}
+	if o.wrote {
+		p.writes[o.waddr]++
+	}
+	switch in.op {
+	case STORE:
+		p.inputs = append(p.inputs, p.steps-p.lastIn)
+		p.lastIn = p.steps
+	case OUTPUT:
+		p.outputs = append(p.outputs, p.steps-p.lastOut)
+		p.lastOut = p.steps
+	}
+	p.follow(c.pos, pc, in, o)
+}
+
+// follow keeps the shadow call stack in step with control flow.
+func (p *Profiler) follow(next int64, pc int64, in instruction, o *operands) {
+	if in.op == ADDITION && in.modes == [3]ParameterMode{IMMEDIATE, IMMEDIATE, RELATIVE} && o.wrote {
+		p.ret = o.wval
+		return
+	}
+	if in.op != JIT && in.op != JIF {
+		return
+	}
+	ret := p.ret
+	p.ret = -1
+	if next == pc+3 {
+		return
+	}
+	for i := len(p.frames) - 1; i >= 0; i-- {
+		if p.frames[i].ret == next {
+			p.stack = p.frames[i].stack
+			p.frames = p.frames[:i]
+			return
+		}
+	}
+	if ret >= 0 {
+		p.frames = append(p.frames, frame{next, ret, p.stack})
+		p.calls[next]++
+		key := stackKey{p.stack, next, ret}
+		id, ok := p.ids[key]
+		if !ok {
+			id = len(p.parents)
+			p.parents = append(p.parents, key)
+			p.ids[key] = id
+		}
+		p.stack = id
+	}
+}
+
+// lineAt decodes the instruction at pc for the report.
+func lineAt(c *Computer, pc int64, in instruction) Line {
+	l := Line{Addr: pc, Op: in.op, Modes: in.modes}
+	for i := 0; i <= in.op.Params(); i++ {
+		w, _ := c.memory.Read(pc + int64(i))
+		l.Words = append(l.Words, w)
+	}
+	return l
+}
+
+// entries returns the subroutine entries of a chain, innermost first. The
+// outermost is always 0 for the program itself.
+func (p *Profiler) entries(stack int) []int64 {
+	chain := []int64{}
+	for ; stack >= 0; stack = p.parents[stack].parent {
+		chain = append(chain, p.parents[stack].entry)
+	}
+	return chain
+}
+
+// counted is an address or opcode with its count, for sorting.
+type counted struct {
+	key   int64
+	count int64
+}
+
+// sortCounts returns m's entries busiest first, addresses ascending on ties.
+func sortCounts(m map[int64]int64) []counted {
+	sorted := make([]counted, 0, len(m))
+	for k, n := range m {
+		sorted = append(sorted, counted{k, n})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].key < sorted[j].key
+	})
+	return sorted
+}
+
+// costSummary describes the instructions spent between successive inputs
+// or outputs.
+func costSummary(costs []int64) string {
+	if len(costs) == 0 {
+		return "none"
+	}
+	total, max, worst := int64(0), int64(0), 0
+	for i, n := range costs {
+		total += n
+		if n > max {
+			max, worst = n, i
+		}
+	}
+	return fmt.Sprintf("%d, %d instructions, mean %.1f, max %d (#%d)",
+		len(costs), total, float64(total)/float64(len(costs)), max, worst)
+}
+
+// WriteReport writes a plain text summary, listing at most top rows in
+// each of the address, subroutine and memory sections.
+func (p *Profiler) WriteReport(w io.Writer, top int) error {
+	bw := bufio.NewWriter(w)
+	percent := func(n int64) float64 {
+		if p.steps == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(p.steps)
+	}
+	limit := func(rows []counted) []counted {
+		if top > 0 && len(rows) > top {
+			return rows[:top]
+		}
+		return rows
+	}
+
+	fmt.Fprintf(bw, "instructions: %d\n", p.steps)
+	fmt.Fprintf(bw, "inputs:       %s\n", costSummary(p.inputs))
+	fmt.Fprintf(bw, "outputs:      %s\n", costSummary(p.outputs))
+
+	ops := make(map[int64]int64, len(p.ops))
+	for op, n := range p.ops {
+		ops[int64(op)] = n
+	}
+	fmt.Fprintln(bw, "\nopcodes:")
+	for _, r := range sortCounts(ops) {
+		fmt.Fprintf(bw, "  %-10s %12d %6.2f%%\n", OpCode(r.key), r.count, percent(r.count))
+	}
+
+	addrs := make(map[int64]int64, len(p.addrs))
+	for a, s := range p.addrs {
+		addrs[a] = s.count
+	}
+	fmt.Fprintln(bw, "\nhot addresses:")
+	for _, r := range limit(sortCounts(addrs)) {
+		fmt.Fprintf(bw, "  %6d %12d %6.2f%%  %s\n", r.key, r.count, percent(r.count), p.addrs[r.key].line.Text())
+	}
+
+	// self time goes to the innermost subroutine, total time to every
+	// subroutine on the stack, counting recursion once
+	self := make(map[int64]int64)
+	total := make(map[int64]int64)
+	for k, n := range p.samples {
+		chain := p.entries(k.stack)
+		self[chain[0]] += n
+		seen := make(map[int64]bool, len(chain))
+		for _, e := range chain {
+			if !seen[e] {
+				seen[e] = true
+				total[e] += n
+			}
+		}
+	}
+	fmt.Fprintln(bw, "\nsubroutines:     calls         self                 total")
+	for _, r := range limit(sortCounts(total)) {
+		fmt.Fprintf(bw, "  %6d %12d %12d %6.2f%% %12d %6.2f%%\n",
+			r.key, p.calls[r.key], self[r.key], percent(self[r.key]), r.count, percent(r.count))
+	}
+
+	fmt.Fprintln(bw, "\nmemory reads:")
+	for _, r := range limit(sortCounts(p.reads)) {
+		fmt.Fprintf(bw, "  %6d %12d\n", r.key, r.count)
+	}
+	fmt.Fprintln(bw, "\nmemory writes:")
+	for _, r := range limit(sortCounts(p.writes)) {
+		fmt.Fprintf(bw, "  %6d %12d\n", r.key, r.count)
+	}
+	return bw.Flush()
+}
+
+// WriteHeatmap writes memory access counts as CSV with a row per address
+// that was read or written, in address order.
+func (p *Profiler) WriteHeatmap(w io.Writer) error {
+	addrs := []int64{}
+	for a := range p.reads {
+		addrs = append(addrs, a)
+	}
+	for a := range p.writes {
+		if _, ok := p.reads[a]; !ok {
+			addrs = append(addrs, a)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "addr,reads,writes,executed")
+	for _, a := range addrs {
+		executed := int64(0)
+		if s := p.addrs[a]; s != nil {
+			executed = s.count
+		}
+		fmt.Fprintf(bw, "%d,%d,%d,%d\n", a, p.reads[a], p.writes[a], executed)
+	}
+	return bw.Flush()
+}
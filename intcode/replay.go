@@ -0,0 +1,282 @@
+package intcode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EventKind says whether an Event was an input or an output.
+type EventKind int
+
+const (
+	// INPUT_EVENT is a value consumed by STORE.
+	INPUT_EVENT EventKind = iota
+	// OUTPUT_EVENT is a value produced by OUTPUT.
+	OUTPUT_EVENT
+)
+
+func (k EventKind) String() string {
+	if k == INPUT_EVENT {
+		return "in"
+	}
+	return "out"
+}
+
+// Event is one value crossing the program's I/O boundary, stamped with the
+// count of instructions executed up to and including the one that moved it.
+type Event struct {
+	Kind  EventKind
+	Step  int64
+	Value int64
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%v %d at step %d", e.Kind, e.Value, e.Step)
+}
+
+// Recorder captures every input consumed and output produced by a Computer
+// so the session can be replayed later. Attach it with
+// Computer.SetRecorder before the first instruction of the session.
+type Recorder struct {
+	program uint64
+	steps   int64
+	halted  bool
+	events  []Event
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// SetRecorder attaches r to c, or stops recording when r is nil. The
+// memory of c when r is attached identifies the program, so a replay can
+// tell it is being run against something else.
+func (c *Computer) SetRecorder(r *Recorder) {
+	if r != nil {
+		r.program = c.memoryHash()
+	}
+	c.recorder = r
+}
+
+// memoryHash fingerprints memory up to the high water mark.
+func (c *Computer) memoryHash() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for a := int64(0); a < c.memory.HighWater(); a++ {
+		v, _ := c.memory.Read(a)
+		for i := range buf {
+			buf[i] = byte(v >> (8 * i))
+		}
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// Events returns what has been recorded so far.
+func (r *Recorder) Events() []Event {
+	return r.events
+}
+
+func (r *Recorder) step(c *Computer, op OpCode, o *operands) {
+	r.steps++
+	switch op {
+	case STORE:
+		r.events = append(r.events, Event{INPUT_EVENT, r.steps, o.wval})
+	case OUTPUT:
+		r.events = append(r.events, Event{OUTPUT_EVENT, r.steps, c.outputs[len(c.outputs)-1]})
+	case QUIT:
+		r.halted = true
+	}
+}
+
+// The replay format is text so a session can be read, diffed and checked
+// in as a regression case: a header, the program fingerprint, one line per
+// event and a last line with the instruction count and whether the program
+// halted or was stopped, usually waiting for input.
+const replayHeader = "intcode replay 1"
+
+// Encode writes the recording to w.
+func (r *Recorder) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, replayHeader)
+	fmt.Fprintf(bw, "program %016x\n", r.program)
+	for _, e := range r.events {
+		fmt.Fprintf(bw, "%v %d %d\n", e.Kind, e.Step, e.Value)
+	}
+	end := "stopped"
+	if r.halted {
+		end = "halted"
+	}
+	fmt.Fprintf(bw, "end %d %s\n", r.steps, end)
+	return bw.Flush()
+}
+
+// Save writes the recording to the file at path.
+func (r *Recorder) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Replay is a recorded session read back by DecodeReplay.
+type Replay struct {
+	Program uint64
+	Events  []Event
+	// Steps is the instruction count the session ended at, and Halted
+	// whether it ended with QUIT rather than being stopped.
+	Steps  int64
+	Halted bool
+}
+
+var ErrReplayFormat = errors.New("not an intcode replay")
+
+// DecodeReplay reads a recording written by Recorder.Encode.
+func DecodeReplay(r io.Reader) (*Replay, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || scanner.Text() != replayHeader {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrReplayFormat
+	}
+	rp := &Replay{}
+	ended := false
+	bad := func(n int) error {
+		return fmt.Errorf("replay line %d: %w", n, ErrReplayFormat)
+	}
+	for n := 2; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if ended || (len(fields) != 2 && len(fields) != 3) {
+			return nil, bad(n)
+		}
+		switch {
+		case fields[0] == "program" && len(fields) == 2:
+			v, err := strconv.ParseUint(fields[1], 16, 64)
+			if err != nil {
+				return nil, bad(n)
+			}
+			rp.Program = v
+		case fields[0] == "end" && len(fields) == 3:
+			steps, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil || (fields[2] != "halted" && fields[2] != "stopped") {
+				return nil, bad(n)
+			}
+			rp.Steps, rp.Halted, ended = steps, fields[2] == "halted", true
+		case (fields[0] == "in" || fields[0] == "out") && len(fields) == 3:
+			step, err1 := strconv.ParseInt(fields[1], 10, 64)
+			value, err2 := strconv.ParseInt(fields[2], 10, 64)
+			if err1 != nil || err2 != nil {
+				return nil, bad(n)
+			}
+			kind := INPUT_EVENT
+			if fields[0] == "out" {
+				kind = OUTPUT_EVENT
+			}
+			rp.Events = append(rp.Events, Event{kind, step, value})
+		default:
+			return nil, bad(n)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !ended {
+		return nil, fmt.Errorf("replay is missing its end line: %w", ErrReplayFormat)
+	}
+	return rp, nil
+}
+
+// LoadReplay reads a recording from the file at path.
+func LoadReplay(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeReplay(f)
+}
+
+// Divergence reports the first point where a replayed session stopped
+// matching its recording.
+type Divergence struct {
+	// Index is the position in the recording of the event that didn't
+	// match, or len(Events) when the events all matched but the ending
+	// didn't.
+	Index int
+	Step  int64
+	Want  string
+	Got   string
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("replay diverged at step %d (event %d): want %s, got %s", d.Step, d.Index, d.Want, d.Got)
+}
+
+// Drive runs c through the recorded session: every recorded input is
+// queued up front and each live input and output is checked against the
+// recording as it happens, including the instruction it happens at. It
+// returns a *Divergence at the first difference and nil once c has ended
+// the way the session did. c should be fresh, in the state it was when the
+// recording started, with no inputs of its own queued.
+func (rp *Replay) Drive(c *Computer) error {
+	if h := c.memoryHash(); h != rp.Program {
+		return &Divergence{Want: fmt.Sprintf("program %016x", rp.Program), Got: fmt.Sprintf("program %016x", h)}
+	}
+	for _, e := range rp.Events {
+		if e.Kind == INPUT_EVENT {
+			c.AddInput(e.Value)
+		}
+	}
+	live := NewRecorder()
+	saved := c.recorder
+	c.SetRecorder(live)
+	defer func() { c.recorder = saved }()
+
+	for live.steps < rp.Steps {
+		if c.finished || c.inputBlocked {
+			break
+		}
+		if err := c.Step(); err != nil {
+			return &Divergence{len(live.events), live.steps, "no error", err.Error()}
+		}
+		if i := len(live.events) - 1; i >= 0 && (live.events[i].Step == live.steps) {
+			if i >= len(rp.Events) {
+				return &Divergence{i, live.steps, "no more I/O", live.events[i].String()}
+			}
+			if live.events[i] != rp.Events[i] {
+				return &Divergence{i, live.steps, rp.Events[i].String(), live.events[i].String()}
+			}
+		}
+	}
+	if len(live.events) < len(rp.Events) {
+		i := len(live.events)
+		return &Divergence{i, live.steps, rp.Events[i].String(), rp.ending(live.steps, c.finished)}
+	}
+	if live.steps != rp.Steps || c.finished != rp.Halted {
+		return &Divergence{len(rp.Events), live.steps, rp.ending(rp.Steps, rp.Halted), rp.ending(live.steps, c.finished)}
+	}
+	return nil
+}
+
+func (rp *Replay) ending(steps int64, halted bool) string {
+	if halted {
+		return fmt.Sprintf("halt after %d steps", steps)
+	}
+	return fmt.Sprintf("stop after %d steps", steps)
+}
@@ -0,0 +1,107 @@
+package intcode
+
+import (
+	"context"
+	"sync"
+)
+
+// Runner executes a Computer in its own goroutine. STORE takes its input
+// from In and every OUTPUT is sent on Out. When the program halts or fails
+// Out is closed and Done is closed after it.
+//
+// In and Out may be replaced before Start, e.g. to feed one runner's Out
+// straight into another's In. The Computer must not be touched while the
+// runner is going.
+type Runner struct {
+	c     *Computer
+	In    chan int64
+	Out   chan int64
+	done  chan struct{}
+	err   error
+	start sync.Once
+}
+
+// NewRunner returns a Runner for c with In and Out buffered to hold buffer
+// values.
+func NewRunner(c *Computer, buffer int) *Runner {
+	return &Runner{
+		c:    c,
+		In:   make(chan int64, buffer),
+		Out:  make(chan int64, buffer),
+		done: make(chan struct{}),
+	}
+}
+
+// Start runs the computer until it halts, fails or ctx is cancelled. If In
+// is closed while the program is waiting for input it fails with
+// ErrInputUnderflow. Only the first call does anything.
+func (r *Runner) Start(ctx context.Context) {
+	r.start.Do(func() {
+		go func() {
+			r.err = r.run(ctx)
+			close(r.Out)
+			close(r.done)
+		}()
+	})
+}
+
+// Done is closed once the runner has stopped.
+func (r *Runner) Done() <-chan struct{} {
+	return r.done
+}
+
+// Wait blocks until the runner has stopped and returns why: nil for a clean
+// halt, the computer's error or the context's.
+func (r *Runner) Wait() error {
+	<-r.done
+	return r.err
+}
+
+// Computer returns the computer being run. Only inspect it after Done.
+func (r *Runner) Computer() *Computer {
+	return r.c
+}
+
+func (r *Runner) run(ctx context.Context) error {
+	c := r.c
+	for n := 0; ; n++ {
+		for len(c.outputs) > 0 {
+			select {
+			case r.Out <- c.outputs[0]:
+				c.PopOutput()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if c.finished {
+			return nil
+		}
+		if n%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if c.inputBlocked && len(c.inputs) == 0 {
+			if err := r.wait(ctx); err != nil {
+				return err
+			}
+		}
+		if err := c.Step(); err != nil {
+			return err
+		}
+	}
+}
+
+// wait queues the next input. A closed In queues nothing, so the following
+// step underflows.
+func (r *Runner) wait(ctx context.Context) error {
+	select {
+	case v, ok := <-r.In:
+		if ok {
+			r.c.AddInput(v)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,28 @@
+package intcode
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRunnerStartTwice(t *testing.T) {
+	// doubles each input until it reads 0
+	program := []int64{3, 15, 1006, 15, 14, 1002, 15, 2, 16, 4, 16, 1105, 1, 0, 99, 0, 0}
+	r := NewRunner(NewComputer(program), 3)
+	r.Start(context.Background())
+	r.Start(context.Background())
+	for _, v := range []int64{1, 2, 3, 0} {
+		r.In <- v
+	}
+	got := []int64{}
+	for v := range r.Out {
+		got = append(got, v)
+	}
+	if err := r.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{2, 4, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+package intcode
+
+import (
+	"context"
+	"fmt"
+)
+
+// MachineState is where a scheduled computer stands after its last turn.
+type MachineState int
+
+const (
+	// READY has more to run.
+	READY MachineState = iota
+	// BLOCKED is waiting for input that hasn't arrived.
+	BLOCKED
+	// HALTED has executed QUIT.
+	HALTED
+	// FAILED stopped with an error.
+	FAILED
+)
+
+func (s MachineState) String() string {
+	switch s {
+	case READY:
+		return "READY"
+	case BLOCKED:
+		return "BLOCKED"
+	case HALTED:
+		return "HALTED"
+	case FAILED:
+		return "FAILED"
+	}
+	return "UNKNOWN"
+}
+
+// MachineStats counts what a computer did under a Scheduler.
+type MachineStats struct {
+	// Steps is instructions executed, Turns the slices it was given and
+	// Waits the turns it spent blocked.
+	Steps int64
+	Turns int64
+	Waits int64
+}
+
+// Idler is implemented by input devices that can tell when their computer
+// is only spinning, like a NetworkCard polling an empty queue.
+type Idler interface {
+	Idle() bool
+}
+
+// Scheduler runs several computers on one goroutine, giving each in turn,
+// in the order they were added, a slice of at most Slice instructions. A
+// turn ends early when the computer halts or blocks for input. The same
+// computers and inputs always run the same way.
+type Scheduler struct {
+	Slice int64
+	// OnIdle, when set, is called whenever a round leaves every computer
+	// idle. It can feed them more work and return true to carry on.
+	OnIdle   func() bool
+	machines []*Computer
+	stats    []MachineStats
+	rounds   int64
+	idles    int64
+	stop     bool
+}
+
+// NewScheduler returns a Scheduler with the given time slice. Round and
+// Run fail with ErrSlice unless it is positive.
+func NewScheduler(slice int64) *Scheduler {
+	return &Scheduler{Slice: slice}
+}
+
+// Add schedules c and returns its id, the index it is known by from then on.
+func (s *Scheduler) Add(c *Computer) int {
+	s.machines = append(s.machines, c)
+	s.stats = append(s.stats, MachineStats{})
+	return len(s.machines) - 1
+}
+
+// Computer returns the computer with the given id.
+func (s *Scheduler) Computer(id int) *Computer {
+	return s.machines[id]
+}
+
+// State returns the state of computer id.
+func (s *Scheduler) State(id int) MachineState {
+	c := s.machines[id]
+	switch {
+	case c.err != nil:
+		return FAILED
+	case c.finished:
+		return HALTED
+	case c.inputBlocked && len(c.inputs) == 0:
+		return BLOCKED
+	}
+	return READY
+}
+
+// Stats returns the counts for computer id.
+func (s *Scheduler) Stats(id int) MachineStats {
+	return s.stats[id]
+}
+
+// Rounds returns how many rounds have run, and how many of them ended with
+// every computer idle.
+func (s *Scheduler) Rounds() (rounds int64, idle int64) {
+	return s.rounds, s.idles
+}
+
+// Stop ends Run after the current turn. It is meant for device callbacks
+// that spot the answer.
+func (s *Scheduler) Stop() {
+	s.stop = true
+}
+
+// idle reports whether computer id has nothing useful to do.
+func (s *Scheduler) idle(id int) bool {
+	switch s.State(id) {
+	case HALTED, BLOCKED:
+		return true
+	}
+	idler, ok := s.machines[id].input.(Idler)
+	return ok && idler.Idle()
+}
+
+func (s *Scheduler) halted() bool {
+	for _, c := range s.machines {
+		if !c.finished {
+			return false
+		}
+	}
+	return true
+}
+
+// turn runs computer id for one slice.
+func (s *Scheduler) turn(id int) error {
+	c := s.machines[id]
+	stats := &s.stats[id]
+	// a blocked computer only gets to run if it has input or a device that
+	// might have some by now
+	if c.finished || (c.inputBlocked && len(c.inputs) == 0 && c.input == nil) {
+		stats.Waits++
+		return nil
+	}
+	stats.Turns++
+	for n := int64(0); n < s.Slice && !c.finished && !s.stop; n++ {
+		if err := c.Step(); err != nil {
+			return fmt.Errorf("computer %d: %w", id, err)
+		}
+		if c.inputBlocked {
+			stats.Waits++
+			break
+		}
+		stats.Steps++
+	}
+	return nil
+}
+
+// Round gives every computer one turn and reports whether all of them were
+// idle afterwards.
+func (s *Scheduler) Round() (bool, error) {
+	if s.Slice <= 0 {
+		return false, ErrSlice
+	}
+	s.rounds++
+	for id := range s.machines {
+		if err := s.turn(id); err != nil {
+			return false, err
+		}
+		if s.stop {
+			return false, nil
+		}
+	}
+	for id := range s.machines {
+		if !s.idle(id) {
+			return false, nil
+		}
+	}
+	s.idles++
+	return true, nil
+}
+
+// Run schedules rounds until Stop is called, a computer fails, every
+// computer has halted or they are all idle and OnIdle doesn't find them
+// more work.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.stop = false
+	for !s.stop {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		idle, err := s.Round()
+		if err != nil {
+			return err
+		}
+		if idle && (s.halted() || s.OnIdle == nil || !s.OnIdle()) {
+			return nil
+		}
+	}
+	return nil
+}
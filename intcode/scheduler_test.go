@@ -0,0 +1,57 @@
+package intcode
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// amplifiers chains a computer per phase on a scheduler the way day 7
+// does, feeding the last one's output back to the first, and returns the
+// last signal the chain produced.
+func amplifiers(t *testing.T, slice int64, program []int64, phases ...int64) int64 {
+	s := NewScheduler(slice)
+	amps := []*Computer{}
+	for _, phase := range phases {
+		amp := NewComputer(program, phase)
+		s.Add(amp)
+		amps = append(amps, amp)
+	}
+	for i := 1; i < len(amps); i++ {
+		amps[i-1].AttachOutput(&Pipe{To: amps[i]})
+	}
+	thrusters := &Pipe{To: amps[0]}
+	amps[len(amps)-1].AttachOutput(thrusters)
+	amps[0].AddInput(0)
+
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for id := range amps {
+		if s.State(id) != HALTED {
+			t.Errorf("slice %d: amp %d is %v", slice, id, s.State(id))
+		}
+	}
+	return thrusters.Last
+}
+
+func TestSchedulerFeedback(t *testing.T) {
+	program := []int64{3, 26, 1001, 26, -4, 26, 3, 27, 1002, 27, 2, 27, 1, 27, 26,
+		27, 4, 27, 1001, 28, -1, 28, 1005, 28, 6, 99, 0, 0, 5}
+	// the answer can't depend on how the amps interleave
+	for _, slice := range []int64{1, 3, 1000} {
+		if got := amplifiers(t, slice, program, 9, 8, 7, 6, 5); got != 139629729 {
+			t.Errorf("slice %d: got %d, want 139629729", slice, got)
+		}
+	}
+}
+
+func TestSchedulerSlice(t *testing.T) {
+	for _, slice := range []int64{0, -1} {
+		s := NewScheduler(slice)
+		s.Add(NewComputer(countdown(10)))
+		if err := s.Run(context.Background()); !errors.Is(err, ErrSlice) {
+			t.Errorf("slice %d: got %v, want ErrSlice", slice, err)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package intcode
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+)
+
+// Snapshot is a frozen copy of a Computer's state. Restoring it doesn't
+// consume it, so one snapshot can seed any number of forks.
+type Snapshot struct {
+	memory       *Memory
+	Pos          int64
+	RelativeBase int64
+	Inputs       []int64
+	Outputs      []int64
+	Finished     bool
+	InputBlocked bool
+	Arithmetic   Arithmetic
+	bigs         map[int64]*big.Int
+	bigOutputs   map[int]*big.Int
+}
+
+// Snapshot captures the current state of c. A failed computer's error is
+// not part of the snapshot.
+func (c *Computer) Snapshot() *Snapshot {
+	return &Snapshot{
+		memory:       c.memory.Clone(),
+		Pos:          c.pos,
+		RelativeBase: c.relativeBase,
+		Inputs:       append([]int64{}, c.inputs...),
+		Outputs:      append([]int64{}, c.outputs...),
+		Finished:     c.finished,
+		InputBlocked: c.inputBlocked,
+		Arithmetic:   c.arith,
+		bigs:         cloneBigs(c.bigs),
+		bigOutputs:   cloneBigOutputs(c.bigOutputs),
+	}
+}
+
+// Restore puts c back into the state held by s. Any tracer stays attached.
+func (c *Computer) Restore(s *Snapshot) {
+	c.memory = s.memory.Clone()
+	c.pos = s.Pos
+	c.relativeBase = s.RelativeBase
+	c.inputs = append([]int64{}, s.Inputs...)
+	c.outputs = append([]int64{}, s.Outputs...)
+	c.finished = s.Finished
+	c.inputBlocked = s.InputBlocked
+	c.bigs = cloneBigs(s.bigs)
+	c.bigOutputs = cloneBigOutputs(s.bigOutputs)
+	c.SetArithmetic(s.Arithmetic)
+	c.err = nil
+	c.decoded = nil
+}
+
+// NewComputerFromSnapshot returns a fresh Computer in the state held by s.
+func NewComputerFromSnapshot(s *Snapshot) *Computer {
+	c := &Computer{}
+	c.Restore(s)
+	return c
+}
+
+// cloneBigs copies the values beyond int64 in memory, keeping nil as nil.
+func cloneBigs(m map[int64]*big.Int) map[int64]*big.Int {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[int64]*big.Int, len(m))
+	for addr, v := range m {
+		clone[addr] = new(big.Int).Set(v)
+	}
+	return clone
+}
+
+// cloneBigOutputs is cloneBigs for the pending outputs.
+func cloneBigOutputs(m map[int]*big.Int) map[int]*big.Int {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[int]*big.Int, len(m))
+	for i, v := range m {
+		clone[i] = new(big.Int).Set(v)
+	}
+	return clone
+}
+
+// The on-disk format is the magic string, a version number and then every
+// field as a varint, with memory stored page by page so scratch memory far
+// from the program doesn't bloat the file, followed by the arithmetic mode
+// and values beyond int64, each as its decimal text.
+const (
+	snapshotMagic   = "ICSNAP"
+	snapshotVersion = 1
+)
+
+var ErrSnapshotFormat = errors.New("not an intcode snapshot")
+
+type snapshotWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (sw *snapshotWriter) int(v int64) {
+	if sw.err == nil {
+		n := binary.PutVarint(sw.buf[:], v)
+		_, sw.err = sw.w.Write(sw.buf[:n])
+	}
+}
+
+func (sw *snapshotWriter) ints(vs []int64) {
+	sw.int(int64(len(vs)))
+	for _, v := range vs {
+		sw.int(v)
+	}
+}
+
+// bigs writes values in key order so equal snapshots encode identically.
+func (sw *snapshotWriter) bigs(values map[int64]*big.Int) {
+	keys := make([]int64, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	sw.int(int64(len(keys)))
+	for _, k := range keys {
+		text := values[k].String()
+		sw.int(k)
+		sw.int(int64(len(text)))
+		if sw.err == nil {
+			_, sw.err = sw.w.WriteString(text)
+		}
+	}
+}
+
+func (sw *snapshotWriter) bool(b bool) {
+	if b {
+		sw.int(1)
+	} else {
+		sw.int(0)
+	}
+}
+
+// Encode writes s to w in the versioned snapshot format.
+func (s *Snapshot) Encode(w io.Writer) error {
+	sw := &snapshotWriter{w: bufio.NewWriter(w)}
+	if _, err := sw.w.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	sw.int(snapshotVersion)
+	sw.int(s.Pos)
+	sw.int(s.RelativeBase)
+	sw.bool(s.Finished)
+	sw.bool(s.InputBlocked)
+	sw.ints(s.Inputs)
+	sw.ints(s.Outputs)
+	sw.int(s.memory.HighWater())
+
+	pages := 0
+	s.memory.eachPage(func(int64, []int64) error { pages++; return nil })
+	sw.int(int64(pages))
+	s.memory.eachPage(func(n int64, page []int64) error {
+		sw.int(n)
+		for _, v := range page {
+			sw.int(v)
+		}
+		return sw.err
+	})
+
+	sw.int(int64(s.Arithmetic))
+	sw.bigs(s.bigs)
+	outputs := make(map[int64]*big.Int, len(s.bigOutputs))
+	for i, v := range s.bigOutputs {
+		outputs[int64(i)] = v
+	}
+	sw.bigs(outputs)
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.w.Flush()
+}
+
+type snapshotReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (sr *snapshotReader) int() int64 {
+	if sr.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(sr.r)
+	if err != nil {
+		sr.err = fmt.Errorf("reading snapshot: %w", err)
+	}
+	return v
+}
+
+func (sr *snapshotReader) ints() []int64 {
+	n := sr.int()
+	if n < 0 {
+		sr.err = ErrSnapshotFormat
+		return nil
+	}
+	vs := []int64{}
+	for i := int64(0); i < n && sr.err == nil; i++ {
+		vs = append(vs, sr.int())
+	}
+	return vs
+}
+
+// bigs calls set with each key and value written by snapshotWriter.bigs.
+func (sr *snapshotReader) bigs(set func(k int64, v *big.Int)) {
+	n := sr.int()
+	for i := int64(0); i < n && sr.err == nil; i++ {
+		k := sr.int()
+		size := sr.int()
+		if sr.err != nil {
+			return
+		}
+		if size <= 0 || size > 1<<20 {
+			sr.err = ErrSnapshotFormat
+			return
+		}
+		text := make([]byte, size)
+		if _, err := io.ReadFull(sr.r, text); err != nil {
+			sr.err = fmt.Errorf("reading snapshot: %w", err)
+			return
+		}
+		v, ok := new(big.Int).SetString(string(text), 10)
+		if !ok {
+			sr.err = ErrSnapshotFormat
+			return
+		}
+		set(k, v)
+	}
+}
+
+// DecodeSnapshot reads a snapshot written by Encode.
+func DecodeSnapshot(r io.Reader) (*Snapshot, error) {
+	sr := &snapshotReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(sr.r, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, ErrSnapshotFormat
+	}
+	version := sr.int()
+	if sr.err == nil && version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	s := &Snapshot{memory: &Memory{}}
+	s.Pos = sr.int()
+	s.RelativeBase = sr.int()
+	s.Finished = sr.int() != 0
+	s.InputBlocked = sr.int() != 0
+	s.Inputs = sr.ints()
+	s.Outputs = sr.ints()
+	highWater := sr.int()
+
+	pages := sr.int()
+	for i := int64(0); i < pages && sr.err == nil; i++ {
+		n := sr.int()
+		if n < 0 {
+			return nil, ErrSnapshotFormat
+		}
+		page := s.memory.page(n, true)
+		for j := range page {
+			page[j] = sr.int()
+		}
+	}
+	s.Arithmetic = Arithmetic(sr.int())
+	sr.bigs(func(k int64, v *big.Int) {
+		if s.bigs == nil {
+			s.bigs = make(map[int64]*big.Int)
+		}
+		s.bigs[k] = v
+	})
+	sr.bigs(func(k int64, v *big.Int) {
+		if s.bigOutputs == nil {
+			s.bigOutputs = make(map[int]*big.Int)
+		}
+		s.bigOutputs[int(k)] = v
+	})
+	if sr.err != nil {
+		return nil, sr.err
+	}
+	s.memory.highWater = highWater
+	return s, nil
+}
+
+// Save writes s to the file at path.
+func (s *Snapshot) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadSnapshot reads a snapshot from the file at path.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return DecodeSnapshot(f)
+}
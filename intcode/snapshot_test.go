@@ -0,0 +1,155 @@
+package intcode
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	program := []int64{
+		1102, 1 << 32, 1 << 32, 20, // [20] = 2^64
+		4, 20, // output it
+		1101, 3, 4, 1000000, // [1000000] = 7, far from the program
+		109, 30, // relative base 30
+		203, -8, // [22] = input
+		104, 5, // output 5
+		3, 23,
+		99,
+		0, 0, 0, 0,
+	}
+	c := NewComputer(program, 11, 12)
+	c.SetArithmetic(BIG)
+	for i := 0; i < 6; i++ {
+		if err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := c.Snapshot().Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	encoded := append([]byte{}, buf.Bytes()...)
+	s, err := DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again bytes.Buffer
+	if err := s.Encode(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Bytes(), encoded) {
+		t.Error("re-encoding a decoded snapshot changed it")
+	}
+
+	d := NewComputerFromSnapshot(s)
+	if d.pos != c.pos || d.relativeBase != 30 || d.Arithmetic() != BIG {
+		t.Errorf("pos %d relative base %d arithmetic %v", d.pos, d.relativeBase, d.Arithmetic())
+	}
+	if !reflect.DeepEqual(d.inputs, []int64{12}) {
+		t.Errorf("inputs %v, want [12]", d.inputs)
+	}
+	if d.MemoryHighWater() != c.MemoryHighWater() {
+		t.Errorf("high water %d, want %d", d.MemoryHighWater(), c.MemoryHighWater())
+	}
+	if v, _ := d.Read(1000000); v != 7 {
+		t.Errorf("[1000000] = %d, want 7", v)
+	}
+	if v, _ := d.Read(22); v != 11 {
+		t.Errorf("[22] = %d, want 11", v)
+	}
+	want := new(big.Int).Lsh(big.NewInt(1), 64)
+	if v, err := d.ReadBig(20); err != nil || v.Cmp(want) != 0 {
+		t.Errorf("[20] = %v, %v, want %v", v, err, want)
+	}
+
+	// both carry on the same way
+	for _, m := range []*Computer{c, d} {
+		if err := m.Run(); err != nil || !m.Finished() {
+			t.Fatalf("run after snapshot: %v", err)
+		}
+		if v := m.PopBigOutput(); v.Cmp(want) != 0 {
+			t.Errorf("first output %v, want %v", v, want)
+		}
+		if v := m.PopOutput(); v != 5 {
+			t.Errorf("second output %d, want 5", v)
+		}
+		if v, _ := m.Read(23); v != 12 {
+			t.Errorf("[23] = %d, want 12", v)
+		}
+	}
+}
+
+// TestSnapshotRestoreDecoded checks a computer that has already run
+// executes the restored code rather than what it decoded before.
+func TestSnapshotRestoreDecoded(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewComputer([]int64{104, 1, 99}).Snapshot().Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s, err := DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// [3] = 1 + 1, with an ADDITION where the restored program has OUTPUT
+	c := NewComputer([]int64{1101, 1, 1, 3, 99})
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	c.Restore(s)
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if c.PendingOutputs() != 1 || c.PopOutput() != 1 {
+		t.Errorf("didn't output the restored program's 1")
+	}
+}
+
+// TestSnapshotDevices checks devices aren't part of a snapshot: they stay
+// attached across Restore and get the replayed output again.
+func TestSnapshotDevices(t *testing.T) {
+	c := NewComputer([]int64{3, 9, 4, 9, 104, 6, 99, 0, 0, 0})
+	input := &feed{4, 5}
+	pipe := &Pipe{}
+	c.AttachInput(input)
+	c.AttachOutput(pipe)
+	s := c.Snapshot()
+
+	for _, want := range []int64{4, 5} {
+		if err := c.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if pipe.Last != 6 || c.PendingOutputs() != 0 {
+			t.Errorf("pipe got %d, %d outputs left queued", pipe.Last, c.PendingOutputs())
+		}
+		if v, _ := c.Read(9); v != want {
+			t.Errorf("[9] = %d, want %d from the device", v, want)
+		}
+		c.Restore(s)
+	}
+	if pipe.Sent != 4 {
+		t.Errorf("pipe sent %d, want 4", pipe.Sent)
+	}
+}
+
+func TestDecodeSnapshotErrors(t *testing.T) {
+	if _, err := DecodeSnapshot(bytes.NewReader([]byte("nonsense"))); !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("bad magic: got %v, want ErrSnapshotFormat", err)
+	}
+	var buf bytes.Buffer
+	if err := NewComputer([]int64{99}).Snapshot().Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := DecodeSnapshot(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("decoded a truncated snapshot")
+	}
+	data[len(snapshotMagic)] = 2 * (snapshotVersion + 1) // varints are zigzag encoded
+	if _, err := DecodeSnapshot(bytes.NewReader(data)); err == nil {
+		t.Error("decoded an unknown version")
+	}
+}
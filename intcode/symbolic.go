@@ -0,0 +1,323 @@
+package intcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrNotLinear  = errors.New("program is not linear in its unknowns")
+	ErrNoSolution = errors.New("no inputs give the target")
+)
+
+// Linear is Const plus the sum of Coef[addr] times the initial value at
+// each unknown address addr.
+type Linear struct {
+	Const int64
+	Coef  map[int64]int64
+}
+
+func constant(v int64) Linear {
+	return Linear{Const: v}
+}
+
+func (l Linear) isConst() bool {
+	return len(l.Coef) == 0
+}
+
+func (l Linear) add(m Linear) Linear {
+	sum := Linear{Const: l.Const + m.Const, Coef: make(map[int64]int64)}
+	for a, k := range l.Coef {
+		sum.Coef[a] += k
+	}
+	for a, k := range m.Coef {
+		sum.Coef[a] += k
+		if sum.Coef[a] == 0 {
+			delete(sum.Coef, a)
+		}
+	}
+	return sum
+}
+
+func (l Linear) scale(k int64) Linear {
+	scaled := Linear{Const: l.Const * k, Coef: make(map[int64]int64)}
+	if k == 0 {
+		return scaled
+	}
+	for a, c := range l.Coef {
+		scaled.Coef[a] = c * k
+	}
+	return scaled
+}
+
+// Eval returns the value of l when the unknowns hold values.
+func (l Linear) Eval(values map[int64]int64) int64 {
+	v := l.Const
+	for a, k := range l.Coef {
+		v += k * values[a]
+	}
+	return v
+}
+
+func (l Linear) String() string {
+	addrs := make([]int64, 0, len(l.Coef))
+	for a := range l.Coef {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	terms := []string{}
+	for _, a := range addrs {
+		term := fmt.Sprintf("[%d]", a)
+		if k := l.Coef[a]; k != 1 {
+			term = strconv.FormatInt(k, 10) + "*" + term
+		}
+		terms = append(terms, term)
+	}
+	if l.Const != 0 || len(terms) == 0 {
+		terms = append(terms, strconv.FormatInt(l.Const, 10))
+	}
+	return strings.Join(terms, " + ")
+}
+
+// symbol is a memory cell during symbolic execution. A cell read through an
+// address that depends on an unknown could hold anything, which is fine as
+// long as it never matters.
+type symbol struct {
+	value   Linear
+	unknown bool
+}
+
+// InputSearch looks for the initial values at some addresses, like day 2's
+// noun and verb, that make a program halt with a target at Result.
+type InputSearch struct {
+	Program []int64
+	// Unknowns are the addresses to fill, each with a value in Lo..Hi.
+	Unknowns []int64
+	Lo       int64
+	Hi       int64
+	Result   int64
+	// MaxSteps bounds each run, or 0 for no limit.
+	MaxSteps int64
+}
+
+// Symbolic runs the program with the unknowns as symbols and returns the
+// value left at Result in terms of them. It only follows straight-line
+// ADDITION and MULTIPLY up to a QUIT, and fails with ErrNotLinear on
+// anything else, on unknowns multiplied together and on code, write
+// addresses or the result depending on unknowns.
+func (s InputSearch) Symbolic() (Linear, error) {
+	mem := make(map[int64]symbol, len(s.Program))
+	for a, v := range s.Program {
+		mem[int64(a)] = symbol{value: constant(v)}
+	}
+	for _, a := range s.Unknowns {
+		mem[a] = symbol{value: Linear{Coef: map[int64]int64{a: 1}}}
+	}
+	fail := func(pc int64, format string, args ...interface{}) (Linear, error) {
+		return Linear{}, fmt.Errorf("%w: %d: %s", ErrNotLinear, pc, fmt.Sprintf(format, args...))
+	}
+	// read returns the cell at the address held in word
+	read := func(word symbol) (symbol, bool) {
+		if word.unknown || !word.value.isConst() {
+			return symbol{unknown: true}, true
+		}
+		if word.value.Const < 0 {
+			return symbol{}, false
+		}
+		return mem[word.value.Const], true
+	}
+
+	pc := int64(0)
+	for steps := int64(0); s.MaxSteps == 0 || steps < s.MaxSteps; steps++ {
+		cell := mem[pc]
+		if cell.unknown || !cell.value.isConst() {
+			return fail(pc, "code depends on an unknown")
+		}
+		in, err := decode(cell.value.Const)
+		if err != nil {
+			return fail(pc, "%v", err)
+		}
+		switch in.op {
+		case QUIT:
+			result := mem[s.Result]
+			if result.unknown {
+				return fail(pc, "result depends on an unknown address")
+			}
+			return result.value, nil
+		case ADDITION, MULTIPLY:
+		default:
+			return fail(pc, "%v isn't straight-line arithmetic", in.op)
+		}
+		if in.modes[0] == RELATIVE || in.modes[1] == RELATIVE || in.modes[2] != POSITION {
+			return fail(pc, "unsupported parameter mode")
+		}
+
+		var args [2]symbol
+		for i := range args {
+			args[i] = mem[pc+int64(i)+1]
+			if in.modes[i] == POSITION {
+				var ok bool
+				if args[i], ok = read(args[i]); !ok {
+					return fail(pc, "negative address")
+				}
+			}
+		}
+		dest := mem[pc+3]
+		if dest.unknown || !dest.value.isConst() || dest.value.Const < 0 {
+			return fail(pc, "write address depends on an unknown")
+		}
+
+		var result symbol
+		switch {
+		case args[0].unknown || args[1].unknown:
+			result.unknown = true
+		case in.op == ADDITION:
+			result.value = args[0].value.add(args[1].value)
+		case args[0].value.isConst():
+			result.value = args[1].value.scale(args[0].value.Const)
+		case args[1].value.isConst():
+			result.value = args[0].value.scale(args[1].value.Const)
+		default:
+			return fail(pc, "unknowns multiplied together")
+		}
+		mem[dest.value.Const] = result
+		pc += 4
+	}
+	return fail(pc, "no QUIT within %d steps", s.MaxSteps)
+}
+
+// Solve returns values in Lo..Hi for the unknowns, in order, that make l
+// equal target. The first solution in the order a brute force search would
+// try them is returned, with earlier unknowns varying slowest.
+func (s InputSearch) Solve(l Linear, target int64) ([]int64, bool) {
+	values := make(map[int64]int64)
+	var solve func(i int) bool
+	solve = func(i int) bool {
+		last := s.Unknowns[len(s.Unknowns)-1]
+		if i == len(s.Unknowns)-1 {
+			values[last] = 0
+			rest := target - l.Eval(values)
+			k := l.Coef[last]
+			if k == 0 {
+				values[last] = s.Lo
+				return rest == 0
+			}
+			if rest%k != 0 || rest/k < s.Lo || rest/k > s.Hi {
+				return false
+			}
+			values[last] = rest / k
+			return true
+		}
+		for v := s.Lo; v <= s.Hi; v++ {
+			values[s.Unknowns[i]] = v
+			if solve(i + 1) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(s.Unknowns) == 0 || !solve(0) {
+		return nil, false
+	}
+	found := make([]int64, len(s.Unknowns))
+	for i, a := range s.Unknowns {
+		found[i] = values[a]
+	}
+	return found, true
+}
+
+// Check runs the program with values at the unknowns and reports whether
+// it halted with target at Result.
+func (s InputSearch) Check(values []int64, target int64) bool {
+	program := append([]int64{}, s.Program...)
+	for i, a := range s.Unknowns {
+		for int64(len(program)) <= a {
+			program = append(program, 0)
+		}
+		program[a] = values[i]
+	}
+	c := NewComputer(program)
+	if err := c.RunContext(context.Background(), s.MaxSteps); err != nil || !c.Finished() {
+		return false
+	}
+	result, err := c.Read(s.Result)
+	return err == nil && result == target
+}
+
+// BruteForce tries every combination of values on all CPUs, and returns
+// the same answer a plain nested loop would: the first that works with
+// earlier unknowns varying slowest.
+func (s InputSearch) BruteForce(target int64) ([]int64, error) {
+	span := s.Hi - s.Lo + 1
+	total := int64(1)
+	for range s.Unknowns {
+		total *= span
+	}
+	combination := func(n int64) []int64 {
+		values := make([]int64, len(s.Unknowns))
+		for i := len(values) - 1; i >= 0; i-- {
+			values[i] = s.Lo + n%span
+			n /= span
+		}
+		return values
+	}
+
+	const chunk = 64
+	var next int64
+	best := total
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				start := atomic.AddInt64(&next, chunk) - chunk
+				if start >= total || start > atomic.LoadInt64(&best) {
+					return
+				}
+				for n := start; n < start+chunk && n < total; n++ {
+					if n > atomic.LoadInt64(&best) {
+						return
+					}
+					if !s.Check(combination(n), target) {
+						continue
+					}
+					for {
+						b := atomic.LoadInt64(&best)
+						if n >= b || atomic.CompareAndSwapInt64(&best, b, n) {
+							break
+						}
+					}
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if best == total {
+		return nil, ErrNoSolution
+	}
+	return combination(best), nil
+}
+
+// Find solves symbolically when the program allows it, checking the answer
+// with a real run, and otherwise falls back to BruteForce.
+func (s InputSearch) Find(target int64) ([]int64, error) {
+	if l, err := s.Symbolic(); err == nil {
+		values, ok := s.Solve(l, target)
+		if !ok {
+			return nil, ErrNoSolution
+		}
+		if s.Check(values, target) {
+			return values, nil
+		}
+	}
+	return s.BruteForce(target)
+}
@@ -0,0 +1,107 @@
+package intcode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// day2Like leaves 300*noun + verb + 12345 at 0, after a first instruction
+// that reads through the noun and verb as addresses and is then overwritten,
+// the way the real day 2 programs start.
+var day2Like = []int64{1, 0, 0, 3, 2, 1, 20, 3, 1, 3, 2, 3, 1, 3, 21, 0, 99, 0, 0, 0, 300, 12345}
+
+func TestSymbolicNotLinear(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		program  []int64
+		unknowns []int64
+		why      string
+	}{
+		{"unknown write address", []int64{1, 5, 5, 0, 99, 7}, []int64{3}, "write address"},
+		{"unknowns multiplied", []int64{2, 5, 6, 0, 99, 0, 0}, []int64{5, 6}, "multiplied"},
+		{"jump", []int64{1105, 1, 4, 0, 99, 0}, []int64{5}, "JIT"},
+		{"result through unknown address", []int64{1, 1, 2, 0, 99}, []int64{1, 2}, "result"},
+		{"code from unknown", []int64{1, 5, 5, 4, 0, 0}, []int64{5}, "code"},
+	} {
+		s := InputSearch{Program: tc.program, Unknowns: tc.unknowns, Lo: 0, Hi: 99, MaxSteps: 100}
+		l, err := s.Symbolic()
+		if !errors.Is(err, ErrNotLinear) {
+			t.Errorf("%s: got %v, %v, want ErrNotLinear", tc.name, l, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.why) {
+			t.Errorf("%s: got %v, want it to mention %q", tc.name, err, tc.why)
+		}
+	}
+}
+
+func TestSymbolicExpression(t *testing.T) {
+	s := InputSearch{Program: day2Like, Unknowns: []int64{1, 2}, Lo: 0, Hi: 99, MaxSteps: 100}
+	l, err := s.Symbolic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := l.String(), "300*[1] + [2] + 12345"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSolveNoSolution(t *testing.T) {
+	s := InputSearch{Program: day2Like, Unknowns: []int64{1, 2}, Lo: 0, Hi: 99, MaxSteps: 100}
+	for _, target := range []int64{0, 12344, 300*99 + 99 + 12346} {
+		l, err := s.Symbolic()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values, ok := s.Solve(l, target); ok {
+			t.Errorf("%d: Solve found %v", target, values)
+		}
+		if values, err := s.BruteForce(target); !errors.Is(err, ErrNoSolution) {
+			t.Errorf("%d: BruteForce got %v, %v, want ErrNoSolution", target, values, err)
+		}
+		if values, err := s.Find(target); !errors.Is(err, ErrNoSolution) {
+			t.Errorf("%d: Find got %v, %v, want ErrNoSolution", target, values, err)
+		}
+	}
+}
+
+// TestSolveMatchesBruteForce checks Solve picks the same answer as trying
+// every combination in order, including when several would do.
+func TestSolveMatchesBruteForce(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		program  []int64
+		unknowns []int64
+		targets  []int64
+	}{
+		{"day 2", day2Like, []int64{1, 2}, []int64{12345, 300*42 + 17 + 12345, 300*99 + 99 + 12345}},
+		// every noun+verb pair summing to the target works
+		{"sum", []int64{1101, 0, 0, 0, 99}, []int64{1, 2}, []int64{0, 50, 99, 150, 198}},
+		// the second unknown is never used, so any value of it works
+		{"unused", []int64{1101, 0, 5, 0, 99}, []int64{1, 9}, []int64{5, 47}},
+		{"negative", []int64{2, 9, 10, 0, 1, 0, 11, 0, 99, 0, -3, 500}, []int64{9, 12}, []int64{500, 497, 203}},
+	} {
+		s := InputSearch{Program: tc.program, Unknowns: tc.unknowns, Lo: 0, Hi: 99, MaxSteps: 100}
+		l, err := s.Symbolic()
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		for _, target := range tc.targets {
+			solved, ok := s.Solve(l, target)
+			brute, err := s.BruteForce(target)
+			if !ok || err != nil {
+				t.Errorf("%s %d: Solve %v %v, BruteForce %v %v", tc.name, target, solved, ok, brute, err)
+				continue
+			}
+			if !reflect.DeepEqual(solved, brute) {
+				t.Errorf("%s %d: Solve got %v, BruteForce %v", tc.name, target, solved, brute)
+			}
+			if !s.Check(solved, target) {
+				t.Errorf("%s %d: %v doesn't check out", tc.name, target, solved)
+			}
+		}
+	}
+}
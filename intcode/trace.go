@@ -0,0 +1,129 @@
+package intcode
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// TraceRecord is one executed instruction. Operands holds the resolved
+// value of each parameter: the value read for inputs and the destination
+// address for the parameter that is written.
+type TraceRecord struct {
+	Step     int64       `json:"step"`
+	PC       int64       `json:"pc"`
+	Op       string      `json:"op"`
+	Raw      int64       `json:"raw"`
+	Operands []int64     `json:"operands"`
+	RB       int64       `json:"rb"`
+	Write    *TraceWrite `json:"write,omitempty"`
+	Input    *int64      `json:"input,omitempty"`
+	Output   *int64      `json:"output,omitempty"`
+}
+
+type TraceWrite struct {
+	Addr  int64 `json:"addr"`
+	Value int64 `json:"value"`
+}
+
+// Tracer writes a TraceRecord as a line of JSON for every instruction a
+// Computer executes, optionally limited to an address range and a set of
+// opcodes. Steps are counted across everything executed, so filtered
+// traces of two runs still line up.
+type Tracer struct {
+	enc     *json.Encoder
+	from    int64
+	to      int64
+	ops     map[OpCode]bool
+	step    int64
+	pending *TraceRecord
+	err     error
+}
+
+// NewTracer returns a Tracer writing to w. Attach it with Computer.SetTracer.
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{enc: json.NewEncoder(w), from: 0, to: -1}
+}
+
+// FilterAddr limits records to instructions at from..to inclusive.
+func (t *Tracer) FilterAddr(from int64, to int64) {
+	t.from, t.to = from, to
+}
+
+// FilterOps limits records to the given opcodes.
+func (t *Tracer) FilterOps(ops ...OpCode) {
+	t.ops = make(map[OpCode]bool)
+	for _, op := range ops {
+		t.ops[op] = true
+	}
+}
+
+// Steps returns the number of instructions executed while attached.
+func (t *Tracer) Steps() int64 {
+	return t.step
+}
+
+// Err returns the first error hit writing the trace.
+func (t *Tracer) Err() error {
+	return t.err
+}
+
+// SetTracer attaches t to c, or detaches tracing when t is nil.
+func (c *Computer) SetTracer(t *Tracer) {
+	c.tracer = t
+}
+
+func (t *Tracer) wants(pc int64, op OpCode) bool {
+	if pc < t.from || (t.to >= 0 && pc > t.to) {
+		return false
+	}
+	return t.ops == nil || t.ops[op]
+}
+
+// begin captures the operands before the instruction can overwrite them.
+func (t *Tracer) begin(c *Computer, in instruction) {
+	t.pending = nil
+	if !t.wants(c.pos, in.op) {
+		return
+	}
+	r := &TraceRecord{PC: c.pos, Op: in.op.String(), Raw: in.raw, RB: c.relativeBase}
+	r.Operands = make([]int64, in.op.Params())
+	for i := range r.Operands {
+		value, _ := c.memory.Read(c.pos + int64(i+1))
+		switch {
+		case in.op.Writes() == i+1 && in.modes[i] == RELATIVE:
+			value += c.relativeBase
+		case in.op.Writes() == i+1:
+		case in.modes[i] == POSITION:
+			value, _ = c.memory.Read(value)
+		case in.modes[i] == RELATIVE:
+			value, _ = c.memory.Read(c.relativeBase + value)
+		}
+		r.Operands[i] = value
+	}
+	t.pending = r
+}
+
+func (t *Tracer) end(c *Computer, in instruction, o *operands) {
+	if o.err != nil || (in.op == STORE && !o.wrote) {
+		// failed or blocked waiting for input, so nothing ran
+		return
+	}
+	t.step++
+	r := t.pending
+	if r == nil || t.err != nil {
+		return
+	}
+	r.Step = t.step
+	if o.wrote {
+		r.Write = &TraceWrite{o.waddr, o.wval}
+	}
+	switch in.op {
+	case STORE:
+		v := o.wval
+		r.Input = &v
+	case OUTPUT:
+		v := c.outputs[len(c.outputs)-1]
+		r.Output = &v
+	}
+	t.err = t.enc.Encode(r)
+}
@@ -0,0 +1,409 @@
+package intcode
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+)
+
+// Transpile translates program into Go source for package pkg. The result
+// defines a Machine whose Run behaves like a Computer running program, with
+// input pulled from its In function and output pushed to Out. For package
+// main it also gets a main that reads inputs from stdin and prints each
+// output on a line of its own.
+//
+// Every basic block found by ControlFlow becomes straight Go code joined by
+// gotos wherever a jump target is known statically. Indirect jumps go through
+// a switch on the target, and anything that isn't compiled, including code
+// the program has since overwritten, runs on an embedded interpreter that
+// hands back to compiled code at the next untouched block.
+func Transpile(program []int64, pkg string) ([]byte, error) {
+	blocks := ControlFlow(program)
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no code reachable from address 0")
+	}
+	starts := make(map[int64]int)
+	for i, b := range blocks {
+		starts[b.Start] = i
+		for _, l := range b.Lines {
+			if _, ok := opTable[l.Op]; !ok || extensions[l.Op] != nil {
+				return nil, fmt.Errorf("%d: cannot transpile %v", l.Addr, l.Op)
+			}
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by intcode transpile. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if pkg == "main" {
+		src.WriteString("import (\n\"bufio\"\n\"fmt\"\n\"os\"\n\"strconv\"\n\"strings\"\n)\n\n")
+	} else {
+		src.WriteString("import \"fmt\"\n\n")
+	}
+
+	fmt.Fprintf(&src, "var image = []int64{%s}\n\n", joinWords(program, ", "))
+	src.WriteString("// blocks holds the start and end of each compiled block\nvar blocks = [][2]int64{\n")
+	for _, b := range blocks {
+		fmt.Fprintf(&src, "{%d, %d},\n", b.Start, b.End())
+	}
+	src.WriteString("}\n\n")
+	src.WriteString(transpileRuntime)
+
+	src.WriteString("func (m *Machine) run() error {\n")
+	src.WriteString("dispatch:\nswitch m.pc {\n")
+	for _, b := range blocks {
+		fmt.Fprintf(&src, "case %d:\ngoto b%d\n", b.Start, b.Start)
+	}
+	src.WriteString("}\n")
+	src.WriteString("interp:\nif halted, err := m.interpret(); halted || err != nil {\nreturn err\n}\ngoto dispatch\n\n")
+
+	for i, b := range blocks {
+		fmt.Fprintf(&src, "b%d:\nm.pc = %d\nif m.dirty[%d] {\ngoto interp\n}\n", b.Start, b.Start, i)
+		terminated := false
+		for _, l := range b.Lines {
+			terminated = transpileLine(&src, l, starts)
+		}
+		if terminated {
+			continue
+		}
+		if i+1 < len(blocks) && blocks[i+1].Start == b.End() {
+			// falls straight into the next label
+			continue
+		}
+		src.WriteString(transpileJump(b.End(), starts))
+	}
+	src.WriteString("}\n")
+
+	if pkg == "main" {
+		src.WriteString(transpileMain)
+	}
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+// transpileJump returns the Go for continuing at target.
+func transpileJump(target int64, starts map[int64]int) string {
+	if _, ok := starts[target]; ok {
+		return fmt.Sprintf("goto b%d\n", target)
+	}
+	return fmt.Sprintf("m.pc = %d\ngoto dispatch\n", target)
+}
+
+// goParam returns a Go expression for the value of parameter n of l.
+func goParam(l Line, n int) string {
+	v := l.Words[n]
+	switch l.Modes[n-1] {
+	case IMMEDIATE:
+		if v < 0 {
+			return fmt.Sprintf("(%d)", v)
+		}
+		return strconv.FormatInt(v, 10)
+	case RELATIVE:
+		return fmt.Sprintf("m.read(m.rb + %d)", v)
+	default:
+		return fmt.Sprintf("m.read(%d)", v)
+	}
+}
+
+// goAddr returns a Go expression for the address parameter n of l writes.
+func goAddr(l Line, n int) string {
+	if l.Modes[n-1] == RELATIVE {
+		return fmt.Sprintf("m.rb + %d", l.Words[n])
+	}
+	return strconv.FormatInt(l.Words[n], 10)
+}
+
+// transpileLine writes the Go for one instruction and reports whether it
+// always leaves the block.
+func transpileLine(src *bytes.Buffer, l Line, starts map[int64]int) bool {
+	next := l.Addr + int64(len(l.Words))
+	fmt.Fprintf(src, "// %d: %s\n", l.Addr, l.Text())
+	if w := l.Op.Writes(); w > 0 && l.Modes[w-1] == IMMEDIATE {
+		if l.Op == STORE {
+			// with no input the Computer blocks before looking at the mode
+			fmt.Fprintf(src, "if _, ok := m.In(); !ok {\nm.pc = %d\nreturn m.fail(%q)\n}\n", l.Addr, ErrInputUnderflow.Error())
+		}
+		fmt.Fprintf(src, "m.pc = %d\nreturn m.fail(%q)\n", l.Addr, ErrImmediateWrite.Error())
+		return true
+	}
+
+	// a write that lands on compiled code hands over to the interpreter
+	write := func(value string) string {
+		return fmt.Sprintf("if m.write(%s, %s) {\nm.pc = %d\ngoto interp\n}\n", goAddr(l, l.Op.Writes()), value, next)
+	}
+
+	switch l.Op {
+	case ADDITION:
+		src.WriteString(write(goParam(l, 1) + " + " + goParam(l, 2)))
+	case MULTIPLY:
+		src.WriteString(write(goParam(l, 1) + " * " + goParam(l, 2)))
+	case LT:
+		src.WriteString(write("b2i(" + goParam(l, 1) + " < " + goParam(l, 2) + ")"))
+	case EQ:
+		src.WriteString(write("b2i(" + goParam(l, 1) + " == " + goParam(l, 2) + ")"))
+	case STORE:
+		fmt.Fprintf(src, "{\nv, ok := m.In()\nif !ok {\nm.pc = %d\nreturn m.fail(%q)\n}\n%s}\n",
+			l.Addr, ErrInputUnderflow.Error(), write("v"))
+	case OUTPUT:
+		fmt.Fprintf(src, "m.Out(%s)\n", goParam(l, 1))
+	case RBO:
+		fmt.Fprintf(src, "m.rb += %s\n", goParam(l, 1))
+	case JIT, JIF:
+		taken, falls := branches(l)
+		test := goParam(l, 1)
+		jump := transpileJump(l.Words[2], starts)
+		if l.Modes[1] != IMMEDIATE {
+			// the Computer reads the target even when it doesn't jump, so a
+			// bad one faults either way
+			if l.Modes[0] != IMMEDIATE {
+				fmt.Fprintf(src, "{\nv := %s\ntarget := %s\n", test, goParam(l, 2))
+				test = "v"
+			} else {
+				fmt.Fprintf(src, "{\ntarget := %s\n_ = target\n", goParam(l, 2))
+			}
+			defer src.WriteString("}\n")
+			jump = "m.pc = target\ngoto dispatch\n"
+		}
+		switch {
+		case taken && !falls:
+			src.WriteString(jump)
+			return true
+		case taken:
+			cond := "!="
+			if l.Op == JIF {
+				cond = "=="
+			}
+			fmt.Fprintf(src, "if %s %s 0 {\n%s}\n", test, cond, jump)
+		}
+	case QUIT:
+		fmt.Fprintf(src, "m.pc = %d\nreturn nil\n", l.Addr)
+		return true
+	}
+	return false
+}
+
+const transpileRuntime = `// Machine runs the transpiled program. In supplies input, returning false
+// when there is none left, and Out receives each output.
+type Machine struct {
+	In  func() (int64, bool)
+	Out func(int64)
+
+	mem   []int64
+	far   map[int64]int64
+	pc    int64
+	rb    int64
+	dirty []bool
+}
+
+// blockOf maps each address inside a compiled block to its index plus one.
+var blockOf []int
+
+func init() {
+	for i, b := range blocks {
+		for a := b[0]; a < b[1]; a++ {
+			for int64(len(blockOf)) <= a {
+				blockOf = append(blockOf, 0)
+			}
+			blockOf[a] = i + 1
+		}
+	}
+}
+
+// NewMachine returns a Machine ready to run the program from the start.
+func NewMachine() *Machine {
+	m := &Machine{mem: append([]int64{}, image...), far: map[int64]int64{}}
+	m.dirty = make([]bool, len(blocks))
+	m.In = func() (int64, bool) { return 0, false }
+	m.Out = func(int64) {}
+	return m
+}
+
+// Run executes the program until it halts or fails.
+func (m *Machine) Run() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(machineError)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	return m.run()
+}
+
+type machineError string
+
+func (e machineError) Error() string {
+	return string(e)
+}
+
+func (m *Machine) fail(msg string) error {
+	return machineError(fmt.Sprintf("intcode: %s at pos %d", msg, m.pc))
+}
+
+func (m *Machine) read(a int64) int64 {
+	if a < 0 {
+		panic(m.fail(fmt.Sprintf("invalid memory address: %d", a)))
+	}
+	if a < int64(len(m.mem)) {
+		return m.mem[a]
+	}
+	return m.far[a]
+}
+
+// write stores v at a and reports whether that changed compiled code.
+func (m *Machine) write(a int64, v int64) bool {
+	if a < 0 {
+		panic(m.fail(fmt.Sprintf("invalid memory address: %d", a)))
+	}
+	if a >= int64(len(m.mem)) {
+		if a >= 1<<20 {
+			m.far[a] = v
+			return false
+		}
+		m.mem = append(m.mem, make([]int64, a+1-int64(len(m.mem)))...)
+	}
+	m.mem[a] = v
+	if a < int64(len(blockOf)) && blockOf[a] != 0 {
+		m.dirty[blockOf[a]-1] = true
+		return true
+	}
+	return false
+}
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// interpret executes instructions one at a time until the program halts or
+// reaches the start of a block whose compiled code is still good.
+func (m *Machine) interpret() (bool, error) {
+	for {
+		if m.pc >= 0 && m.pc < int64(len(blockOf)) && blockOf[m.pc] != 0 &&
+			blocks[blockOf[m.pc]-1][0] == m.pc && !m.dirty[blockOf[m.pc]-1] {
+			return false, nil
+		}
+		raw := m.read(m.pc)
+		if raw < 0 {
+			return false, m.fail("unknown op code")
+		}
+		modes := raw / 100
+		addr := func(n int64) int64 {
+			mode := modes
+			for i := int64(1); i < n; i++ {
+				mode /= 10
+			}
+			v := m.read(m.pc + n)
+			switch mode % 10 {
+			case 0:
+				return v
+			case 2:
+				return m.rb + v
+			}
+			panic(m.fail("write in immediate mode"))
+		}
+		param := func(n int64) int64 {
+			mode := modes
+			for i := int64(1); i < n; i++ {
+				mode /= 10
+			}
+			if mode%10 == 1 {
+				return m.read(m.pc + n)
+			}
+			return m.read(addr(n))
+		}
+		for d := modes; d > 0; d /= 10 {
+			if d%10 > 2 {
+				return false, m.fail("invalid parameter mode")
+			}
+		}
+		switch raw % 100 {
+		case 1:
+			m.write(addr(3), param(1)+param(2))
+			m.pc += 4
+		case 2:
+			m.write(addr(3), param(1)*param(2))
+			m.pc += 4
+		case 3:
+			v, ok := m.In()
+			if !ok {
+				return false, m.fail("input underflow")
+			}
+			m.write(addr(1), v)
+			m.pc += 2
+		case 4:
+			m.Out(param(1))
+			m.pc += 2
+		case 5:
+			if v, target := param(1), param(2); v != 0 {
+				m.pc = target
+			} else {
+				m.pc += 3
+			}
+		case 6:
+			if v, target := param(1), param(2); v == 0 {
+				m.pc = target
+			} else {
+				m.pc += 3
+			}
+		case 7:
+			m.write(addr(3), b2i(param(1) < param(2)))
+			m.pc += 4
+		case 8:
+			m.write(addr(3), b2i(param(1) == param(2)))
+			m.pc += 4
+		case 9:
+			m.rb += param(1)
+			m.pc += 2
+		case 99:
+			return true, nil
+		default:
+			return false, m.fail("unknown op code")
+		}
+	}
+}
+
+`
+
+const transpileMain = `
+// main feeds the program integers read from stdin, separated by commas or
+// whitespace, and prints each output on its own line.
+func main() {
+	in := bufio.NewScanner(os.Stdin)
+	pending := []int64{}
+	m := NewMachine()
+	m.In = func() (int64, bool) {
+		for len(pending) == 0 {
+			if !in.Scan() {
+				return 0, false
+			}
+			for _, f := range strings.Fields(strings.ReplaceAll(in.Text(), ",", " ")) {
+				v, err := strconv.ParseInt(f, 10, 64)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "bad input %q\n", f)
+					os.Exit(1)
+				}
+				pending = append(pending, v)
+			}
+		}
+		v := pending[0]
+		pending = pending[1:]
+		return v, true
+	}
+	m.Out = func(v int64) {
+		fmt.Println(v)
+	}
+	if err := m.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
@@ -0,0 +1,208 @@
+package intcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// goTool returns the go command that built the test, or skips t.
+func goTool(t *testing.T) string {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	tool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	if _, err := exec.LookPath(tool); err != nil {
+		if tool, err = exec.LookPath("go"); err != nil {
+			t.Skip("no go command")
+		}
+	}
+	return tool
+}
+
+// transpileCase is a program to run both ways, with what the Computer did.
+type transpileCase struct {
+	program []int64
+	inputs  []int64
+	c       *Computer
+	err     error
+	outputs []int64
+}
+
+// transpileDump is added to each transpiled package so the driver can see
+// the memory it ends with.
+const transpileDump = `package %s
+
+func Memory(m *Machine) []int64 {
+	return m.mem
+}
+`
+
+// transpileBuild writes each case as its own package of a throwaway module,
+// with a driver that runs every one and prints its outputs, error and
+// nonzero memory, and builds the lot into a single binary.
+func transpileBuild(t *testing.T, tool string, cases []*transpileCase) string {
+	dir := t.TempDir()
+	files := map[string]string{"go.mod": "module transpiled\n"}
+	var driver bytes.Buffer
+	driver.WriteString("package main\n\nimport (\n\"fmt\"\n")
+	for i := range cases {
+		fmt.Fprintf(&driver, "p%d \"transpiled/p%d\"\n", i, i)
+	}
+	driver.WriteString(")\n\nfunc main() {\n")
+	for i, tc := range cases {
+		pkg := fmt.Sprintf("p%d", i)
+		src, err := Transpile(tc.program, pkg)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.program, err)
+		}
+		files[pkg+"/prog.go"] = string(src)
+		files[pkg+"/dump.go"] = fmt.Sprintf(transpileDump, pkg)
+		fmt.Fprintf(&driver, `{
+	fmt.Println(%d, "run")
+	inputs := %#v
+	m := %s.NewMachine()
+	m.In = func() (int64, bool) {
+		if len(inputs) == 0 {
+			return 0, false
+		}
+		v := inputs[0]
+		inputs = inputs[1:]
+		return v, true
+	}
+	m.Out = func(v int64) { fmt.Println(%d, "out", v) }
+	if err := m.Run(); err != nil {
+		fmt.Println(%d, "err", err)
+	}
+	for a, v := range %s.Memory(m) {
+		if v != 0 {
+			fmt.Println(%d, "mem", a, v)
+		}
+	}
+}
+`, i, append([]int64{}, tc.inputs...), pkg, i, i, pkg, i)
+	}
+	driver.WriteString("}\n")
+	files["main.go"] = driver.String()
+
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin := filepath.Join(dir, "transpiled")
+	build := exec.Command(tool, "build", "-o", bin, ".")
+	build.Dir = dir
+	build.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=", "GOWORK=off")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v\n%s", err, out)
+	}
+	return bin
+}
+
+// TestTranspileMatchesComputer cross-checks the transpiler and the VM on
+// generated programs that halt, block for input or fault within fuzzSteps,
+// comparing outputs, final memory and how they stopped.
+func TestTranspileMatchesComputer(t *testing.T) {
+	tool := goTool(t)
+
+	cases := []*transpileCase{{program: countdown(10)}}
+	rng := rand.New(rand.NewSource(17))
+	data := make([]byte, 128)
+	for tries := 0; len(cases) < 200 && tries < 100000; tries++ {
+		rng.Read(data)
+		program, inputs := genProgram(data)
+		if _, err := Transpile(program, "p"); err != nil {
+			continue
+		}
+		c := NewComputer(program, inputs...)
+		if err := c.RunContext(context.Background(), fuzzSteps); !errors.Is(err, ErrBudgetExceeded) {
+			cases = append(cases, &transpileCase{program: program, inputs: inputs})
+		}
+	}
+	for _, tc := range cases {
+		tc.c = NewComputer(tc.program, tc.inputs...)
+		tc.err = tc.c.RunContext(context.Background(), fuzzSteps)
+		for tc.c.PendingOutputs() > 0 {
+			tc.outputs = append(tc.outputs, tc.c.PopOutput())
+		}
+	}
+
+	bin := transpileBuild(t, tool, cases)
+	// a transpiler bug can leave a program looping where the Computer halted
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin).Output()
+	if err != nil {
+		// the last program to start is the one that didn't finish
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		for n := len(lines) - 1; n >= 0; n-- {
+			if f := strings.Fields(lines[n]); len(f) == 2 && f[1] == "run" {
+				i, _ := strconv.Atoi(f[0])
+				t.Fatalf("%v: %v, Computer stopped with %v", cases[i].program, err, cases[i].err)
+			}
+		}
+		t.Fatalf("running the transpiled programs: %v", err)
+	}
+
+	outputs := make([][]int64, len(cases))
+	stopped := make([]string, len(cases))
+	memory := make([]map[int64]int64, len(cases))
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.Fields(line)
+		i, _ := strconv.Atoi(f[0])
+		switch f[1] {
+		case "out":
+			v, _ := strconv.ParseInt(f[2], 10, 64)
+			outputs[i] = append(outputs[i], v)
+		case "err":
+			stopped[i] = strings.Join(f[2:], " ")
+		case "mem":
+			a, _ := strconv.ParseInt(f[2], 10, 64)
+			v, _ := strconv.ParseInt(f[3], 10, 64)
+			if memory[i] == nil {
+				memory[i] = map[int64]int64{}
+			}
+			memory[i][a] = v
+		}
+	}
+
+	for i, tc := range cases {
+		for a := range memory[i] {
+			if a >= tc.c.MemoryHighWater() {
+				t.Errorf("%v: [%d] = %d, Computer never wrote there", tc.program, a, memory[i][a])
+			}
+		}
+		for a := int64(0); a < tc.c.MemoryHighWater(); a++ {
+			if want, _ := tc.c.Read(a); memory[i][a] != want {
+				t.Errorf("%v: [%d] = %d, Computer %d", tc.program, a, memory[i][a], want)
+			}
+		}
+		if fmt.Sprint(outputs[i]) != fmt.Sprint(tc.outputs) {
+			t.Errorf("%v: outputs %v, Computer %v", tc.program, outputs[i], tc.outputs)
+		}
+		switch {
+		case tc.c.Finished() && stopped[i] != "":
+			t.Errorf("%v: halts on the Computer but got %s", tc.program, stopped[i])
+		case tc.c.InputBlocked() && !strings.Contains(stopped[i], ErrInputUnderflow.Error()):
+			t.Errorf("%v: blocks on the Computer but got %q", tc.program, stopped[i])
+		case tc.err != nil && stopped[i] == "":
+			t.Errorf("%v: fails on the Computer with %v but not transpiled", tc.program, tc.err)
+		}
+	}
+}